@@ -0,0 +1,22 @@
+// A single service declaration that can be run by any of the runners, so the
+// graceful shutdown of os/signal, FX and bivrost can be compared, e.g.
+// `go run ./cmd/runner -runner fx`.
+
+package main
+
+import (
+	"flag"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/app"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvservice"
+)
+
+func main() {
+	runnerName := flag.String("runner", app.RUNNER_SIGNAL, "the runner of the service: signal, fx or bivrost")
+	flag.Parse()
+
+	kvservice.Main(*runnerName, kvservice.Options{
+		Servers: []kvservice.Server{{Label: "0", Address: ":8088"}},
+		Workers: true,
+	})
+}
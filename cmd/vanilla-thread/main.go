@@ -1,54 +1,20 @@
-// A basic implementation of service that manage multiple threads.
+// A basic implementation of service that manage multiple threads, shutting
+// down every one of them gracefully on termination.
 
 package main
 
 import (
-	"fmt"
-	"log"
-	"net/http"
-	"sync"
-	"time"
-
-	"github.com/luthfikw/example.graceful-shutdown/internal/httprouter"
-	"github.com/luthfikw/example.graceful-shutdown/internal/iredis"
+	"github.com/luthfikw/example.graceful-shutdown/internal/app"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvservice"
 )
 
-const API_DURATION = 7 * time.Second
-
 func main() {
-	redisClient, err := iredis.NewRedis()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	httpHandler := httprouter.NewHTTPServerMux("0", API_DURATION, redisClient)
-
-	var wg sync.WaitGroup
-	wg.Add(2)
-
-	go func() {
-		spawnServer1(httpHandler)
-		wg.Done()
-	}()
-
-	go func() {
-		spawnServer2(httpHandler)
-		wg.Done()
-	}()
-
-	wg.Wait()
-}
-
-func spawnServer1(httpHandler http.Handler) {
-	fmt.Printf("Starting server #1 at port 8080\n")
-	if err := http.ListenAndServe(":8080", httpHandler); err != nil {
-		log.Println(err)
-	}
-}
-
-func spawnServer2(httpHandler http.Handler) {
-	fmt.Printf("Starting server #2 at port 8081\n")
-	if err := http.ListenAndServe(":8081", httpHandler); err != nil {
-		log.Println(err)
-	}
+	// both servers share the redis client, which is only closed once they
+	// have been shut down.
+	kvservice.Main(app.RUNNER_SIGNAL, kvservice.Options{
+		Servers: []kvservice.Server{
+			{Label: "1", Address: ":8080"},
+			{Label: "2", Address: ":8081"},
+		},
+	})
 }
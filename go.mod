@@ -6,7 +6,9 @@ require (
 	github.com/go-redis/redis/v8 v8.11.5
 	github.com/koinworks/asgard-bivrost v1.4.3
 	github.com/koinworks/asgard-heimdal v1.5.114
+	github.com/prometheus/client_golang v1.4.1
 	go.uber.org/fx v1.20.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -29,7 +31,6 @@ require (
 	github.com/luthfikw/structs v1.1.2 // indirect
 	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
 	github.com/philhofer/fwd v1.1.1 // indirect
-	github.com/prometheus/client_golang v1.4.1 // indirect
 	github.com/prometheus/client_model v0.2.0 // indirect
 	github.com/prometheus/common v0.9.1 // indirect
 	github.com/prometheus/procfs v0.0.8 // indirect
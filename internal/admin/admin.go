@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+	"github.com/luthfikw/example.graceful-shutdown/internal/signals"
+)
+
+var ErrShutdownRequested = errors.New("shutdown has already been requested")
+
+type Phase string
+
+const (
+	PhaseRunning   Phase = "running"
+	PhaseDraining  Phase = "draining"
+	PhaseDisposing Phase = "disposing"
+	PhaseStopped   Phase = "stopped"
+	PhaseFailed    Phase = "failed"
+)
+
+var phaseOrder = map[Phase]int{
+	PhaseRunning:   0,
+	PhaseDraining:  1,
+	PhaseDisposing: 2,
+	PhaseStopped:   3,
+	PhaseFailed:    3,
+}
+
+// lifecyclePhases maps the lifecycle states into the phases, the starting
+// state is reported as running since the admin server is up by then.
+var lifecyclePhases = map[lifecycle.State]Phase{
+	lifecycle.StateDraining: PhaseDraining,
+	lifecycle.StateStopping: PhaseDisposing,
+	lifecycle.StateStopped:  PhaseStopped,
+	lifecycle.StateFailed:   PhaseFailed,
+}
+
+type Status struct {
+	Phase             Phase               `json:"phase"`
+	StartedAt         time.Time           `json:"started_at"`
+	ShutdownRequested bool                `json:"shutdown_requested"`
+	Components        []shutdown.Progress `json:"components"`
+}
+
+// Admin keeps the phase of the service, and triggers the shutdown the same
+// way as a termination signal, so every runner goes through its usual path.
+type Admin struct {
+	budget   *shutdown.Budget
+	recorder *shutdown.Recorder
+
+	// Trigger starts the shutdown, it sends SIGTERM to the process by
+	// default. The runners with their own signal handler must replace it,
+	// e.g. by signals.Handler.RequestShutdown, or the termination signal
+	// sent by kubernetes afterwards counts as the second one.
+	Trigger func() error
+
+	mu        sync.Mutex
+	phase     Phase
+	startedAt time.Time
+	requested bool
+}
+
+// New follows the components through the recorder, the budget is shortened
+// when the shutdown is requested with a timeout, it may be nil.
+func New(budget *shutdown.Budget, recorder *shutdown.Recorder) *Admin {
+	return &Admin{
+		budget:    budget,
+		recorder:  recorder,
+		Trigger:   signalSelf,
+		phase:     PhaseRunning,
+		startedAt: time.Now(),
+	}
+}
+
+// SetPhase only moves the phase forward, so the hooks running in parallel
+// can report their phase in any order.
+func (ox *Admin) SetPhase(phase Phase) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if phaseOrder[phase] > phaseOrder[ox.phase] {
+		logger.Infof("admin: the service is %s.", phase)
+		ox.phase = phase
+	}
+}
+
+// Follow moves the phase along with the transitions of the lifecycle.
+func (ox *Admin) Follow(lc *lifecycle.Lifecycle) {
+	lc.OnTransition(func(from lifecycle.State, to lifecycle.State) {
+		if phase, ok := lifecyclePhases[to]; ok {
+			ox.SetPhase(phase)
+		}
+	})
+}
+
+func (ox *Admin) Phase() Phase {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return ox.phase
+}
+
+func (ox *Admin) Status() *Status {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return &Status{
+		Phase:             ox.phase,
+		StartedAt:         ox.startedAt,
+		ShutdownRequested: ox.requested,
+		Components:        ox.recorder.Progress(),
+	}
+}
+
+func (ox *Admin) Components() []shutdown.Progress {
+	return ox.recorder.Progress()
+}
+
+// RequestShutdown triggers the shutdown once, a positive timeout shortens the
+// shutdown budget beforehand.
+func (ox *Admin) RequestShutdown(timeout time.Duration) error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if ox.requested || ox.phase != PhaseRunning {
+		return ErrShutdownRequested
+	}
+
+	if timeout > 0 && ox.budget != nil && !ox.budget.Shorten(timeout) {
+		logger.Infof("admin: the shutdown timeout %s is ignored, the budget is %s.", timeout, ox.budget.Remaining())
+	}
+
+	if err := ox.Trigger(); err != nil {
+		return fmt.Errorf("failed to trigger the shutdown: %w", err)
+	}
+
+	logger.Info("admin: the shutdown has been requested.")
+	ox.requested = true
+	return nil
+}
+
+func signalSelf() error {
+	return signals.Raise(syscall.SIGTERM)
+}
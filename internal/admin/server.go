@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+// DEFAULT_ADDRESS only listens on the loopback, the endpoints are not
+// authenticated.
+const DEFAULT_ADDRESS = "127.0.0.1:9091"
+
+var ErrNotLoopback = errors.New("admin address must be a loopback address")
+
+type Config struct {
+	Address string
+}
+
+// LoadConfig reads ADMIN_ADDRESS, which must resolve to the loopback.
+func LoadConfig() (*Config, error) {
+	config := &Config{
+		Address: DEFAULT_ADDRESS,
+	}
+
+	if v, ok := os.LookupEnv("ADMIN_ADDRESS"); ok {
+		config.Address = v
+	}
+
+	if err := checkLoopback(config.Address); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+func checkLoopback(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid ADMIN_ADDRESS '%s': %w", address, err)
+	}
+
+	if host == "localhost" {
+		return nil
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%w: '%s'", ErrNotLoopback, address)
+	}
+
+	return nil
+}
+
+// Server serves the admin endpoints on its own port, it's meant to be stopped
+// after everything else, so the progress can be followed until the end.
+type Server struct {
+	admin  *Admin
+	server *http.Server
+}
+
+func NewServer(config *Config, admin *Admin) *Server {
+	server := &Server{
+		admin: admin,
+	}
+
+	serverMux := http.NewServeMux()
+	serverMux.HandleFunc("/admin/shutdown", server.handleShutdown)
+	serverMux.HandleFunc("/admin/status", server.handleStatus)
+	serverMux.HandleFunc("/admin/components", server.handleComponents)
+
+	server.server = &http.Server{
+		Addr:    config.Address,
+		Handler: serverMux,
+	}
+
+	return server
+}
+
+// Start binds the address before serving, so a taken port fails right away.
+func (ox *Server) Start() error {
+	if err := checkLoopback(ox.server.Addr); err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", ox.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind the admin server at '%s': %w", ox.server.Addr, err)
+	}
+
+	go func() {
+		logger.Infof("admin: server started at '%s'.", ox.server.Addr)
+		err := ox.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errf("admin: server stopped unexpectedly: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+func (ox *Server) Shutdown(ctx context.Context) error {
+	return ox.server.Shutdown(ctx)
+}
+
+// Stop marks the service as stopped, then shuts the server down within the
+// ctx, see shutdown.Budget.Grace.
+func (ox *Server) Stop(ctx context.Context) error {
+	ox.admin.SetPhase(PhaseStopped)
+
+	return ox.Shutdown(ctx)
+}
+
+// handleShutdown accepts an optional timeout as a duration (e.g. "10s"),
+// either from the query or from a JSON body.
+func (ox *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeError(w, 405, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	var payload struct {
+		Timeout string `json:"timeout"`
+	}
+	payload.Timeout = r.URL.Query().Get("timeout")
+	if payload.Timeout == "" && r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeError(w, 400, fmt.Errorf("the request payload is not valid: %w", err))
+			return
+		}
+	}
+
+	var timeout time.Duration
+	if payload.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(payload.Timeout)
+		if err != nil || timeout <= 0 {
+			writeError(w, 400, fmt.Errorf("invalid timeout '%s'", payload.Timeout))
+			return
+		}
+	}
+
+	if err := ox.admin.RequestShutdown(timeout); err != nil {
+		status := 500
+		if errors.Is(err, ErrShutdownRequested) {
+			status = 409
+		}
+
+		writeError(w, status, err)
+		return
+	}
+
+	writeJSON(w, 202, ox.admin.Status())
+}
+
+func (ox *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		writeError(w, 405, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	writeJSON(w, 200, ox.admin.Status())
+}
+
+func (ox *Server) handleComponents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.Header().Set("Allow", "GET")
+		writeError(w, 405, fmt.Errorf("method %s is not allowed", r.Method))
+		return
+	}
+
+	writeJSON(w, 200, ox.admin.Components())
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{
+		"error": err.Error(),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,280 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+	"github.com/luthfikw/example.graceful-shutdown/internal/health"
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+	"github.com/luthfikw/example.graceful-shutdown/internal/signals"
+	"github.com/luthfikw/example.graceful-shutdown/internal/startup"
+)
+
+var (
+	ErrUnknownRunner = errors.New("unknown runner")
+	ErrUnknownPhase  = errors.New("unknown shutdown phase")
+)
+
+const (
+	RUNNER_SIGNAL  = "signal"
+	RUNNER_FX      = "fx"
+	RUNNER_BIVROST = "bivrost"
+)
+
+// phaseOrder is the order the phases are shut down in, the pre-stop phase is
+// left out since it's the drain done before any disposal.
+var phaseOrder = []shutdown.Phase{
+	shutdown.PhaseHTTPDrain,
+	shutdown.PhaseWorkers,
+	shutdown.PhaseResources,
+}
+
+func phaseRank(phase shutdown.Phase) int {
+	for i, p := range phaseOrder {
+		if p == phase {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Runner runs the service until it's shut down, then returns the report of
+// the shutdown. Every runner drains and disposes the same declaration, only
+// the way they're driven differs.
+type Runner interface {
+	Run(svc *Service) (*shutdown.ShutdownReport, error)
+}
+
+// Component is disposed within the share of its phase, the components of a
+// phase are only disposed after those of the earlier phases when the runner
+// supports ordering.
+type Component struct {
+	Name     string
+	Phase    shutdown.Phase
+	Disposer component.ContextDisposableComponent
+
+	// Starter is started before the servers, it defaults to the Disposer
+	// when the Disposer is Startable.
+	Starter component.Startable
+
+	// Critical components are disposed even when the shutdown is aborted.
+	Critical bool
+}
+
+func (ox Component) starter() component.Startable {
+	if ox.Starter != nil {
+		return ox.Starter
+	}
+
+	starter, _ := ox.Disposer.(component.Startable)
+	return starter
+}
+
+type Server struct {
+	Name   string
+	Server *http.Server
+}
+
+// Service declares the servers and components once, so it can be run by any
+// of the runners.
+type Service struct {
+	Budget       *shutdown.Budget
+	Lifecycle    *lifecycle.Lifecycle
+	Recorder     *shutdown.Recorder
+	HardDeadline *shutdown.HardDeadline
+
+	// HealthChecker is drained before the servers are shut down, it may be
+	// nil.
+	HealthChecker *health.Checker
+
+	// Tracker reports the draining progress of the in-flight requests, it
+	// may be nil.
+	Tracker *inflight.Tracker
+
+	servers    []Server
+	components []Component
+	sequence   *startup.Sequence
+	drainOnce  sync.Once
+	watchOnce  sync.Once
+
+	mu      sync.Mutex
+	trigger func() error
+}
+
+func NewService(budget *shutdown.Budget) *Service {
+	return &Service{
+		Budget:       budget,
+		Lifecycle:    lifecycle.New(),
+		Recorder:     shutdown.NewRecorder(),
+		HardDeadline: shutdown.NewHardDeadline(),
+	}
+}
+
+// AddServer declares the http server, its requests derive from the hard
+// deadline unless it has its own BaseContext.
+func (ox *Service) AddServer(name string, server *http.Server) {
+	if server.BaseContext == nil {
+		server.BaseContext = ox.HardDeadline.BaseContext
+	}
+
+	ox.servers = append(ox.servers, Server{
+		Name:   name,
+		Server: server,
+	})
+	ox.Recorder.Expect(name)
+}
+
+func (ox *Service) AddComponent(c Component) error {
+	if c.Name == "" {
+		return shutdown.ErrEmptyName
+	}
+
+	if phaseRank(c.Phase) < 0 {
+		return fmt.Errorf("%w: '%s' of '%s'", ErrUnknownPhase, c.Phase, c.Name)
+	}
+
+	ox.components = append(ox.components, c)
+	ox.Recorder.Expect(c.Name)
+	return nil
+}
+
+// RequestShutdown starts the shutdown the way the runner does on the first
+// termination signal, e.g. for the admin trigger. The runners that only handle
+// the signals by themselves get a SIGTERM.
+func (ox *Service) RequestShutdown() error {
+	ox.mu.Lock()
+	trigger := ox.trigger
+	ox.mu.Unlock()
+
+	if trigger == nil {
+		return signals.Raise(syscall.SIGTERM)
+	}
+
+	return trigger()
+}
+
+func (ox *Service) setTrigger(trigger func() error) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.trigger = trigger
+}
+
+// servable returns the http servers for servergroup.New.
+func (ox *Service) servable() []*http.Server {
+	servers := make([]*http.Server, 0, len(ox.servers))
+	for _, s := range ox.servers {
+		servers = append(servers, s.Server)
+	}
+
+	return servers
+}
+
+// componentsByPhase groups the components by phase, in the order the phases
+// are shut down in.
+func (ox *Service) componentsByPhase() [][]Component {
+	phases := make([][]Component, len(phaseOrder))
+	for _, c := range ox.components {
+		rank := phaseRank(c.Phase)
+		phases[rank] = append(phases[rank], c)
+	}
+
+	return phases
+}
+
+// start starts the components of the last phase first, the reverse of their
+// shutdown order. When one of them fails, those already started are disposed
+// in the reverse order. A termination signal stops the startup.
+func (ox *Service) start() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ox.sequence = startup.NewSequence(ox.Budget.Total)
+	phases := ox.componentsByPhase()
+	for rank := len(phases) - 1; rank >= 0; rank-- {
+		for _, c := range phases[rank] {
+			ox.sequence.Add(c.Name, c.starter(), c.Disposer)
+		}
+	}
+
+	if err := ox.sequence.Start(ctx); err != nil {
+		ox.Lifecycle.Advance(lifecycle.StateFailed)
+		return err
+	}
+
+	return nil
+}
+
+// rollback disposes the started components when the servers fail to start.
+func (ox *Service) rollback() error {
+	return ox.sequence.Rollback()
+}
+
+// drain marks the service as draining, then waits for the load balancer to
+// drop the instance within the pre-stop phase. It's only done once, the
+// concurrent callers wait for it to end.
+func (ox *Service) drain(ctx context.Context) {
+	ox.drainOnce.Do(func() {
+		ox.Lifecycle.Advance(lifecycle.StateDraining)
+		if ox.HealthChecker == nil {
+			return
+		}
+
+		fmt.Println("draining the servers...")
+		ctx, cancel := ox.Budget.Phase(ctx, shutdown.PhasePreStop)
+		defer cancel()
+
+		ox.HealthChecker.Drain(ctx)
+	})
+}
+
+// shutdownServer shuts the server down within the http drain phase, the
+// requests still running past it are aborted through the hard deadline.
+func (ox *Service) shutdownServer(ctx context.Context, s Server) error {
+	ox.Lifecycle.Advance(lifecycle.StateStopping)
+
+	ctx, cancel := ox.Budget.Phase(ctx, shutdown.PhaseHTTPDrain)
+	defer cancel()
+
+	if ox.Tracker != nil {
+		ox.watchOnce.Do(func() {
+			go ox.Tracker.Watch(ctx)
+		})
+	}
+
+	logger.Infof("app: shutting down '%s'...", s.Name)
+	return ox.HardDeadline.Shutdown(ctx, s.Server.Shutdown)
+}
+
+// waitRequests waits for the in-flight requests within the http drain phase,
+// for the servers that stop by themselves, e.g. bivrost's gateway. The ones
+// still running past the phase are aborted through the hard deadline.
+func (ox *Service) waitRequests(ctx context.Context) {
+	ox.Lifecycle.Advance(lifecycle.StateStopping)
+
+	ctx, cancel := ox.Budget.Phase(ctx, shutdown.PhaseHTTPDrain)
+	defer cancel()
+
+	ox.Tracker.Watch(ctx)
+	if ctx.Err() != nil {
+		ox.HardDeadline.Expire()
+	}
+}
+
+// dispose disposes the component within the share of its phase, it leaves
+// the lifecycle to the runner and the servers, which know when the draining
+// is over.
+func (ox *Service) dispose(ctx context.Context, c Component) error {
+	return ox.Budget.Wrap(c.Phase, c.Disposer).DisposeContext(ctx)
+}
@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+	"github.com/luthfikw/example.graceful-shutdown/internal/health"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+// events records the hooks in the order they're done.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (ox *events) add(event string) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.list = append(ox.list, event)
+}
+
+func (ox *events) get() []string {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return append([]string(nil), ox.list...)
+}
+
+// runAtOnce runs the hooks at once, the way bivrost does.
+func runAtOnce(hooks ...func(ctx context.Context)) {
+	var wg sync.WaitGroup
+	for _, hook := range hooks {
+		wg.Add(1)
+		go func(hook func(ctx context.Context)) {
+			defer wg.Done()
+			hook(context.Background())
+		}(hook)
+	}
+	wg.Wait()
+}
+
+func TestPhaseGatesOrder(t *testing.T) {
+	log := &events{}
+	gates := newPhaseGates(shutdown.NewBudget(time.Minute))
+
+	record := func(name string, duration time.Duration) func(ctx context.Context) {
+		return func(ctx context.Context) {
+			time.Sleep(duration)
+			log.add(name)
+		}
+	}
+
+	// registered in the reverse order, with the earlier phases the slowest.
+	runAtOnce(
+		gates.hook(shutdown.PhaseResources, record("redis.client", 0)),
+		gates.hook(shutdown.PhaseWorkers, record("component-1", 0)),
+		gates.hook(shutdown.PhaseHTTPDrain, record("http.server", 60*time.Millisecond)),
+		gates.hook(shutdown.PhaseHTTPDrain, record(INFLIGHT_REQUESTS, 30*time.Millisecond)),
+	)
+
+	want := []string{INFLIGHT_REQUESTS, "http.server", "component-1", "redis.client"}
+	if got := log.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("hooks = %v, want %v", got, want)
+	}
+}
+
+func TestPhaseGatesBudget(t *testing.T) {
+	log := &events{}
+	gates := newPhaseGates(shutdown.NewBudget(50 * time.Millisecond))
+
+	release := make(chan struct{})
+	defer close(release)
+
+	// a server hook stuck past the budget doesn't keep the resources from
+	// being disposed.
+	stuck := gates.hook(shutdown.PhaseHTTPDrain, func(ctx context.Context) {
+		<-release
+	})
+	go stuck(context.Background())
+
+	startedAt := time.Now()
+	gates.hook(shutdown.PhaseResources, func(ctx context.Context) {
+		log.add("redis.client")
+	})(context.Background())
+
+	if elapsed := time.Since(startedAt); elapsed < 40*time.Millisecond || elapsed > time.Second {
+		t.Errorf("waited %s for the stuck hook, want the budget", elapsed)
+	}
+
+	if got := log.get(); len(got) != 1 {
+		t.Errorf("hooks = %v, want the resources disposed", got)
+	}
+}
+
+func TestServiceDrainOnce(t *testing.T) {
+	svc := NewService(shutdown.NewBudget(time.Minute))
+	svc.HealthChecker = health.NewChecker(50 * time.Millisecond)
+	svc.Lifecycle.Advance(lifecycle.StateReady)
+
+	var transitions int
+	svc.Lifecycle.OnTransition(func(from lifecycle.State, to lifecycle.State) {
+		transitions++
+	})
+
+	// every caller waits for the single pre-stop delay.
+	startedAt := time.Now()
+	runAtOnce(svc.drain, svc.drain, svc.drain)
+	elapsed := time.Since(startedAt)
+
+	if elapsed < 50*time.Millisecond || elapsed > 90*time.Millisecond {
+		t.Errorf("drained in %s, want the pre-stop delay once", elapsed)
+	}
+
+	if transitions != 1 || svc.Lifecycle.State() != lifecycle.StateDraining {
+		t.Errorf("%d transition(s) to %s, want one to %s", transitions, svc.Lifecycle.State(), lifecycle.StateDraining)
+	}
+}
+
+func TestServiceDisposeKeepsTheLifecycle(t *testing.T) {
+	svc := NewService(shutdown.NewBudget(time.Minute))
+	svc.Lifecycle.Advance(lifecycle.StateReady)
+	svc.Lifecycle.Advance(lifecycle.StateDraining)
+
+	c := Component{
+		Name:  "component-1",
+		Phase: shutdown.PhaseWorkers,
+		Disposer: component.DisposeContextFunc(func(ctx context.Context) error {
+			return nil
+		}),
+	}
+	if err := svc.dispose(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+
+	// the readiness stays failed and the requests accepted until the servers
+	// are shut down.
+	if state := svc.Lifecycle.State(); state != lifecycle.StateDraining {
+		t.Errorf("state = %s, want %s", state, lifecycle.StateDraining)
+	}
+}
@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/koinworks/asgard-bivrost/service"
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+	"github.com/koinworks/asgard-heimdal/libs/serror"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+// INFLIGHT_REQUESTS names the hook waiting for the requests of bivrost's
+// gateway.
+const INFLIGHT_REQUESTS = "inflight.requests"
+
+// BivrostRunner drives the service with bivrost's termination hooks and
+// threads. Bivrost runs every hook at once, so the hooks of a phase wait for
+// those of the earlier phases, the components are only disposed once the
+// servers have been shut down.
+type BivrostRunner struct {
+	server *service.Server
+}
+
+func NewBivrostRunner(server *service.Server) *BivrostRunner {
+	return &BivrostRunner{
+		server: server,
+	}
+}
+
+func (ox *BivrostRunner) Run(svc *Service) (*shutdown.ShutdownReport, error) {
+	gates := newPhaseGates(svc.Budget)
+
+	for _, c := range svc.components {
+		c := c
+		ox.server.RegisterTrivialTerminationHook(c.Name, gates.hook(c.Phase, svc.Recorder.Hook(c.Name, func(ctx context.Context) error {
+			return svc.dispose(ctx, c)
+		})))
+	}
+
+	// bivrost stops its gateway by itself, the hook only waits for the
+	// requests still running.
+	if svc.Tracker != nil {
+		ox.server.RegisterTrivialTerminationHook(INFLIGHT_REQUESTS, gates.hook(shutdown.PhaseHTTPDrain, svc.Recorder.Hook(INFLIGHT_REQUESTS, func(ctx context.Context) error {
+			svc.drain(ctx)
+			svc.waitRequests(ctx)
+			return nil
+		})))
+	}
+
+	for _, s := range svc.servers {
+		s := s
+
+		// the hook is counted in its phase before bivrost starts, so the
+		// components can't be disposed before the thread hands it over.
+		hook := gates.hook(shutdown.PhaseHTTPDrain, svc.Recorder.Hook(s.Name, func(ctx context.Context) error {
+			svc.drain(ctx)
+			return svc.shutdownServer(ctx, s)
+		}))
+
+		ox.server.RegisterThread(s.Name, func(ctx context.Context, terminationCallbackFNChan chan<- func(ctx context.Context)) (errx serror.SError) {
+			terminationCallbackFNChan <- hook
+
+			logger.Infof("app: starting '%s' at '%s'.", s.Name, s.Server.Addr)
+			err := s.Server.ListenAndServe()
+			if err != nil && err != http.ErrServerClosed {
+				errx = serror.NewFromErrorc(err, fmt.Sprintf("Failed to start '%s'", s.Name))
+			}
+
+			return
+		})
+	}
+
+	if err := svc.start(); err != nil {
+		return nil, err
+	}
+
+	// bivrost doesn't report when it's up, the service is ready once it's
+	// about to start.
+	svc.Lifecycle.Advance(lifecycle.StateReady)
+
+	err := ox.server.Start(context.Background())
+	report := svc.Recorder.Report()
+	svc.Lifecycle.Finish(err, report.Err())
+	if err != nil {
+		return report, err
+	}
+
+	return report, report.Err()
+}
+
+// phaseGates orders the hooks run at once by phase, the hooks of a phase wait
+// for every hook of the earlier phases, at most until the end of the budget.
+type phaseGates struct {
+	budget *shutdown.Budget
+	phases []sync.WaitGroup
+}
+
+func newPhaseGates(budget *shutdown.Budget) *phaseGates {
+	return &phaseGates{
+		budget: budget,
+		phases: make([]sync.WaitGroup, len(phaseOrder)),
+	}
+}
+
+// hook counts the fn in its phase right away, the returned hook waits for the
+// earlier phases before calling it.
+func (ox *phaseGates) hook(phase shutdown.Phase, fn func(ctx context.Context)) func(ctx context.Context) {
+	rank := phaseRank(phase)
+	ox.phases[rank].Add(1)
+
+	return func(ctx context.Context) {
+		defer ox.phases[rank].Done()
+
+		ox.wait(ctx, rank)
+		fn(ctx)
+	}
+}
+
+func (ox *phaseGates) wait(ctx context.Context, rank int) {
+	ctx, cancel := ox.budget.Context(ctx)
+	defer cancel()
+
+	for i := 0; i < rank; i++ {
+		done := make(chan struct{})
+		go func(phase *sync.WaitGroup) {
+			phase.Wait()
+			close(done)
+		}(&ox.phases[i])
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			logger.Errf("app: the hooks of the '%s' phase are still running, %+v", phaseOrder[i], ctx.Err())
+			return
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package app
+
+import (
+	"context"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+	"go.uber.org/fx"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/servergroup"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+// FxRunner drives the service with fx.Lifecycle, the hooks are stopped one
+// after another in the reverse order of their phases.
+type FxRunner struct {
+	// Options are added to the fx app, e.g. fx.NopLogger.
+	Options []fx.Option
+}
+
+func NewFxRunner(options ...fx.Option) *FxRunner {
+	return &FxRunner{
+		Options: options,
+	}
+}
+
+func (ox *FxRunner) Run(svc *Service) (*shutdown.ShutdownReport, error) {
+	app := fx.New(
+		fx.StopTimeout(svc.Budget.Total),
+		fx.Options(ox.Options...),
+		fx.Invoke(func(lc fx.Lifecycle, shutdowner fx.Shutdowner) {
+			appendHooks(lc, shutdowner, svc)
+			svc.setTrigger(func() error {
+				return shutdowner.Shutdown()
+			})
+		}),
+	)
+	if err := app.Err(); err != nil {
+		svc.Lifecycle.Advance(lifecycle.StateFailed)
+		return nil, err
+	}
+
+	// the signals are handled from here on, before the startup, so the one
+	// received right after it isn't left to the default action.
+	done := app.Done()
+
+	if err := svc.start(); err != nil {
+		return nil, err
+	}
+
+	startCtx, cancelStart := context.WithTimeout(context.Background(), app.StartTimeout())
+	defer cancelStart()
+
+	// fx stops the hooks appended before the failed one by itself, which
+	// already disposes the started components.
+	if err := app.Start(startCtx); err != nil {
+		svc.Lifecycle.Advance(lifecycle.StateFailed)
+		return nil, err
+	}
+	svc.Lifecycle.Advance(lifecycle.StateReady)
+
+	<-done
+
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), app.StopTimeout())
+	defer cancelStop()
+
+	// unlike app.Run, stopping it manually lets us report the failed hooks.
+	err := app.Stop(stopCtx)
+	svc.Lifecycle.Finish(err)
+	return svc.Recorder.Report(), err
+}
+
+// appendHooks appends the hooks of the last phase first, since fx stops them
+// in the reverse order. The drain is appended last, so it's stopped first.
+func appendHooks(lc fx.Lifecycle, shutdowner fx.Shutdowner, svc *Service) {
+	phases := svc.componentsByPhase()
+	for rank := len(phases) - 1; rank >= 0; rank-- {
+		for _, c := range phases[rank] {
+			c := c
+			lc.Append(fx.Hook{
+				OnStop: func(ctx context.Context) error {
+					return svc.Recorder.Run(ctx, c.Name, func(ctx context.Context) error {
+						return svc.dispose(ctx, c)
+					})
+				},
+			})
+		}
+	}
+
+	group := servergroup.New(svc.servable()...)
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			if err := group.Start(); err != nil {
+				return err
+			}
+
+			// a server stopping unexpectedly shuts the whole app down.
+			go func() {
+				err := <-group.Err()
+				logger.Errf("app: %+v", err)
+				if err := shutdowner.Shutdown(); err != nil {
+					logger.Errf("app: %+v", err)
+				}
+			}()
+
+			return nil
+		},
+	})
+
+	for _, s := range svc.servers {
+		s := s
+		lc.Append(fx.Hook{
+			OnStop: func(ctx context.Context) error {
+				return svc.Recorder.Run(ctx, s.Name, func(ctx context.Context) error {
+					return svc.shutdownServer(ctx, s)
+				})
+			},
+		})
+	}
+
+	lc.Append(fx.Hook{
+		OnStop: func(ctx context.Context) error {
+			svc.drain(ctx)
+			return nil
+		},
+	})
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"os"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/servergroup"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+	"github.com/luthfikw/example.graceful-shutdown/internal/signals"
+)
+
+// SignalRunner drives the service with os/signal directly, the components
+// are disposed by the orchestrator, in parallel within a phase.
+type SignalRunner struct {
+	Policy signals.Policy
+}
+
+func NewSignalRunner() *SignalRunner {
+	return &SignalRunner{
+		Policy: signals.DefaultPolicy(),
+	}
+}
+
+func (ox *SignalRunner) Run(svc *Service) (*shutdown.ShutdownReport, error) {
+	orchestrator := shutdown.NewWithRecorder(svc.Recorder)
+	if err := register(svc, orchestrator); err != nil {
+		return nil, err
+	}
+
+	drainCtx, cancelDrain := context.WithCancel(context.Background())
+	defer cancelDrain()
+
+	// the second signal skips the remaining non-critical components, while
+	// the third one exits right away. The signals are handled before the
+	// startup, so the one received right after it isn't left to the default
+	// action, which kills the process.
+	signalHandler := signals.NewHandler(ox.Policy)
+	signalHandler.Abort = func(sig os.Signal) {
+		cancelDrain()
+		orchestrator.Abort()
+	}
+	stopSignals := signalHandler.Notify()
+	defer stopSignals()
+
+	svc.setTrigger(func() error {
+		signalHandler.RequestShutdown()
+		return nil
+	})
+
+	if err := svc.start(); err != nil {
+		return nil, err
+	}
+
+	group := servergroup.New(svc.servable()...)
+	if err := group.Start(); err != nil {
+		svc.Lifecycle.Advance(lifecycle.StateFailed)
+		if rollbackErr := svc.rollback(); rollbackErr != nil {
+			logger.Errf("app: %+v", rollbackErr)
+		}
+		return nil, err
+	}
+	svc.Lifecycle.Advance(lifecycle.StateReady)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	select {
+	case action := <-signalHandler.Shutdown():
+		if action == signals.ActionImmediateShutdown {
+			// skip the draining and don't wait for anything.
+			cancel()
+		} else {
+			svc.drain(drainCtx)
+		}
+
+	case err := <-group.Err():
+		logger.Errf("app: %+v", err)
+	}
+
+	ctx, cancelBudget := svc.Budget.Context(ctx)
+	defer cancelBudget()
+
+	svc.Lifecycle.Advance(lifecycle.StateStopping)
+	report, err := orchestrator.Shutdown(ctx)
+	svc.Lifecycle.Finish(err)
+	return report, err
+}
+
+// register makes every component depend on those of the later phases, so the
+// orchestrator disposes the phases in order.
+func register(svc *Service, orchestrator *shutdown.Orchestrator) error {
+	var later []string
+	phases := svc.componentsByPhase()
+	for rank := len(phases) - 1; rank >= 0; rank-- {
+		var names []string
+		for _, c := range phases[rank] {
+			c := c
+			err := orchestrator.RegisterContext(c.Name, component.DisposeContextFunc(func(ctx context.Context) error {
+				return svc.dispose(ctx, c)
+			}), later...)
+			if err != nil {
+				return err
+			}
+
+			if c.Critical {
+				if err := orchestrator.SetCritical(c.Name); err != nil {
+					return err
+				}
+			}
+
+			names = append(names, c.Name)
+		}
+
+		// the servers are shut down within the first phase, before anything
+		// they're using is disposed.
+		if phaseOrder[rank] == shutdown.PhaseHTTPDrain {
+			for _, s := range svc.servers {
+				s := s
+				err := orchestrator.RegisterContext(s.Name, component.DisposeContextFunc(func(ctx context.Context) error {
+					return svc.shutdownServer(ctx, s)
+				}), later...)
+				if err != nil {
+					return err
+				}
+
+				names = append(names, s.Name)
+			}
+		}
+
+		later = append(later, names...)
+	}
+
+	return nil
+}
@@ -1,83 +1,188 @@
 package bvrouter
 
 import (
+	"context"
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis/v8"
-	bvmodels "github.com/koinworks/asgard-bivrost/models"
 	"github.com/koinworks/asgard-bivrost/service"
 	"github.com/koinworks/asgard-heimdal/libs/serror"
-	"github.com/koinworks/asgard-heimdal/utils/utinterface"
-)
 
-var (
-	successMessage = map[string]string{
-		"en": "Success",
-		"id": "Berhasil",
-	}
-	failedMessage = map[string]string{
-		"en": "Request is not valid",
-		"id": "Permintaan tidak valid",
-	}
-	errorMessage = map[string]string{
-		"en": "Oops! Something went wrong, please try again later",
-		"id": "Ups! Ada yang tidak beres, coba lagi nanti",
-	}
+	"github.com/luthfikw/example.graceful-shutdown/internal/envelope"
+	"github.com/luthfikw/example.graceful-shutdown/internal/fault"
+	"github.com/luthfikw/example.graceful-shutdown/internal/i18n"
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvapi"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/metrics"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
 )
 
-func SetupBivrostRouter(label string, apiDuration time.Duration, svc *service.Service, redisClient *redis.Client) {
-	svc.Get("/", func(ctx *service.Context) service.Result {
-		fmt.Printf("server '%s' got the request...\n", label)
-		defer func() {
-			fmt.Printf("server '%s' complete the request.\n", label)
-		}()
+// handlerFunc returns the response instead of writing it, so the status can
+// be observed. The handler works under requestCtx rather than the context of
+// bivrost, see router.handle.
+type handlerFunc func(ctx *service.Context, requestCtx context.Context) (int, envelope.Body)
 
-		isSlow := utinterface.ToBool(ctx.Query("slow"), false)
-		if isSlow {
-			time.Sleep(apiDuration)
-		}
+type router struct {
+	label        string
+	injector     *fault.Injector
+	tracker      *inflight.Tracker
+	respond      *responder
+	hardDeadline *shutdown.HardDeadline
+	appMetrics   *metrics.Metrics
+	appLifecycle *lifecycle.Lifecycle
+}
 
-		result := redisClient.Get(ctx.Context(), "test")
-		if err := result.Err(); err != nil {
-			ctx.CaptureSErrors(serror.NewFromErrorc(err, "failed to get data from redis"))
-			return ctx.JSONResponse(500, bvmodels.ResponseBody{
-				Message: errorMessage,
-			})
+func SetupBivrostRouter(label string, injector *fault.Injector, svc *service.Service, store kvstore.KeyValueStore, storeConfig *kvstore.Config, tracker *inflight.Tracker, catalog *i18n.Catalog, hardDeadline *shutdown.HardDeadline, appMetrics *metrics.Metrics, appLifecycle *lifecycle.Lifecycle) {
+	api := kvapi.New(fault.Store(store), storeConfig)
+	respond := &responder{catalog: catalog}
+	rt := &router{
+		label:        label,
+		injector:     injector,
+		tracker:      tracker,
+		respond:      respond,
+		hardDeadline: hardDeadline,
+		appMetrics:   appMetrics,
+		appLifecycle: appLifecycle,
+	}
+
+	svc.Get("/kv", rt.handle("GET", "/kv", func(ctx *service.Context, requestCtx context.Context) (int, envelope.Body) {
+		result, err := api.List(requestCtx, queryString(ctx, "prefix"))
+		if err != nil {
+			return respond.error(ctx, requestCtx, err)
 		}
 
-		return ctx.JSONResponse(200, bvmodels.ResponseBody{
-			Message: successMessage,
-			Data:    result.Val(),
-		})
-	})
+		return respond.success(ctx, requestCtx, result)
+	}))
 
-	svc.Post("/", func(ctx *service.Context) service.Result {
-		var payload struct {
-			Value string `json:"value"`
+	svc.Get("/kv/:key", rt.handle("GET", "/kv/{key}", func(ctx *service.Context, requestCtx context.Context) (int, envelope.Body) {
+		result, err := api.Get(requestCtx, ctx.Param("key"))
+		if err != nil {
+			return respond.error(ctx, requestCtx, err)
 		}
+
+		return respond.success(ctx, requestCtx, result)
+	}))
+
+	svc.Put("/kv/:key", rt.handle("PUT", "/kv/{key}", func(ctx *service.Context, requestCtx context.Context) (int, envelope.Body) {
+		var payload kvapi.PutRequest
 		if err := ctx.BodyJSONBind(&payload); err != nil {
 			ctx.CaptureSErrors(serror.NewFromErrorc(err, "failed to read the request payload"))
-			return ctx.JSONResponse(400, bvmodels.ResponseBody{
-				Message: failedMessage,
-			})
+			return respond.failure(ctx, requestCtx, 400, nil)
+		}
+
+		result, err := api.Put(requestCtx, ctx.Param("key"), payload)
+		if err != nil {
+			return respond.error(ctx, requestCtx, err)
 		}
 
-		isSlow := utinterface.ToBool(ctx.Query("slow"), false)
-		if isSlow {
-			time.Sleep(apiDuration)
+		return respond.success(ctx, requestCtx, result)
+	}))
+
+	svc.Delete("/kv/:key", rt.handle("DELETE", "/kv/{key}", func(ctx *service.Context, requestCtx context.Context) (int, envelope.Body) {
+		result, err := api.Delete(requestCtx, ctx.Param("key"))
+		if err != nil {
+			return respond.error(ctx, requestCtx, err)
 		}
 
-		result := redisClient.Set(ctx.Context(), "test", payload.Value, time.Hour)
-		if err := result.Err(); err != nil {
-			ctx.CaptureSErrors(serror.NewFromErrorc(err, "failed to write data to redis"))
-			return ctx.JSONResponse(500, bvmodels.ResponseBody{
-				Message: errorMessage,
+		return respond.success(ctx, requestCtx, result)
+	}))
+}
+
+// handle tracks and observes the request, then rejects it unless the
+// lifecycle accepts new work, or injects the faults of the route before
+// running the handler. The handler works under the returned context since it
+// carries the request ID and the simulated store failure. The context is also
+// bound to the hard deadline, since bivrost doesn't let it derive from a base
+// context.
+func (ox *router) handle(method string, route string, handler handlerFunc) func(ctx *service.Context) service.Result {
+	return func(ctx *service.Context) service.Result {
+		done := ox.tracker.Begin(method, route)
+		defer done()
+
+		startedAt := time.Now()
+		fmt.Printf("server '%s' got the request...\n", ox.label)
+		defer func() {
+			fmt.Printf("server '%s' complete the request.\n", ox.label)
+		}()
+
+		boundCtx, cancel := ox.hardDeadline.Bind(ctx.Context())
+		defer cancel()
+		boundCtx = envelope.ContextWithRequestID(boundCtx, envelope.RequestIDOf(ctx.Request()))
+
+		var (
+			status int
+			body   envelope.Body
+		)
+		requestCtx := boundCtx
+		err := ox.appLifecycle.Accept()
+		if err == nil {
+			requestCtx, err = ox.injector.Inject(boundCtx, method, route, func(name string) string {
+				return queryString(ctx, name)
 			})
 		}
+		if err != nil {
+			status, body = ox.respond.error(ctx, boundCtx, err)
+		} else {
+			status, body = handler(ctx, requestCtx)
+		}
+
+		ox.appMetrics.ObserveRequest(method, route, status, time.Since(startedAt))
+		return ctx.JSONResponse(status, body)
+	}
+}
+
+// responder builds the same envelope as the net/http mux. Bivrost's context
+// doesn't expose the response headers, so the request ID and the retry hint
+// are only sent in the body.
+type responder struct {
+	catalog *i18n.Catalog
+}
+
+func (ox *responder) success(ctx *service.Context, requestCtx context.Context, data interface{}) (int, envelope.Body) {
+	return 200, envelope.Body{
+		Message:   ox.catalog.Localize(acceptLanguage(ctx), envelope.MessageSuccess, nil),
+		Data:      data,
+		RequestID: envelope.RequestID(requestCtx),
+	}
+}
+
+// error only exposes the detail of the client errors, the same way as the
+// net/http mux does.
+func (ox *responder) error(ctx *service.Context, requestCtx context.Context, err error) (int, envelope.Body) {
+	status := kvapi.StatusCode(err)
+	if status >= 500 {
+		ctx.CaptureSErrors(serror.NewFromErrorc(err, "failed to access the store"))
+		return ox.failure(ctx, requestCtx, status, nil)
+	}
+
+	return ox.failure(ctx, requestCtx, status, err.Error())
+}
+
+func (ox *responder) failure(ctx *service.Context, requestCtx context.Context, status int, data interface{}) (int, envelope.Body) {
+	args := i18n.Args{"method": method(ctx)}
+	message := ox.catalog.Localize(acceptLanguage(ctx), envelope.MessageOf(status), args)
+	return status, envelope.Failure(status, message, data, envelope.RequestID(requestCtx))
+}
+
+func acceptLanguage(ctx *service.Context) string {
+	if r := ctx.Request(); r != nil {
+		return r.Header.Get("Accept-Language")
+	}
+
+	return ""
+}
+
+func method(ctx *service.Context) string {
+	if r := ctx.Request(); r != nil {
+		return r.Method
+	}
+
+	return ""
+}
 
-		return ctx.JSONResponse(200, bvmodels.ResponseBody{
-			Message: successMessage,
-		})
-	})
+func queryString(ctx *service.Context, name string) string {
+	value, _ := interface{}(ctx.Query(name)).(string)
+	return value
 }
@@ -1,25 +1,159 @@
 package component
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
 	"time"
 
 	"github.com/koinworks/asgard-heimdal/libs/logger"
 )
 
+// ErrDisposeTimeout is reported when a component doesn't finish its disposal
+// before its deadline, as opposed to a component failing by itself.
+var ErrDisposeTimeout = errors.New("dispose timed out")
+
+// PanicError is returned in place of a panic raised during disposal, so the
+// remaining components can still be disposed.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+// NewPanicError captures the stack trace of the current goroutine, it's meant
+// to be called from the deferred recover.
+func NewPanicError(value interface{}) *PanicError {
+	return &PanicError{
+		Value: value,
+		Stack: debug.Stack(),
+	}
+}
+
+func (ox *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", ox.Value, ox.Stack)
+}
+
+// SafeCall calls the fn, a panic raised by the fn is recovered and returned
+// as a PanicError.
+func SafeCall(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+
+	return fn()
+}
+
 type DisposableComponent interface {
 	Dispose() error
 }
 
+type ContextDisposableComponent interface {
+	DisposeContext(ctx context.Context) error
+}
+
+// Startable is started before the service takes any request, a component
+// that fails to start is not disposed, while those started before it are.
+type Startable interface {
+	Start(ctx context.Context) error
+}
+
+// StartFunc adapts an ordinary function into a Startable.
+type StartFunc func(ctx context.Context) error
+
+func (fn StartFunc) Start(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// DisposeFunc adapts an ordinary function into a DisposableComponent.
+type DisposeFunc func() error
+
+func (fn DisposeFunc) Dispose() error {
+	return fn()
+}
+
+// DisposeContextFunc adapts an ordinary function into a
+// ContextDisposableComponent.
+type DisposeContextFunc func(ctx context.Context) error
+
+func (fn DisposeContextFunc) DisposeContext(ctx context.Context) error {
+	return fn(ctx)
+}
+
+// WithContext adapts a legacy DisposableComponent into a
+// ContextDisposableComponent. When the context is done before Dispose
+// returns, the context error is returned while Dispose keeps running in the
+// background.
+func WithContext(instance DisposableComponent) ContextDisposableComponent {
+	if ctxInstance, ok := instance.(ContextDisposableComponent); ok {
+		return ctxInstance
+	}
+
+	return DisposeContextFunc(func(ctx context.Context) error {
+		done := make(chan error, 1)
+		go func() {
+			// the panic must be recovered here, since it can't be caught
+			// from the caller's goroutine.
+			done <- SafeCall(instance.Dispose)
+		}()
+
+		select {
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
 type Component struct {
 	Label           string
+	StartDuration   time.Duration
+	StartError      error
 	DisposeDuration time.Duration
 	DisposeError    error
 }
 
+func (ox *Component) Start(ctx context.Context) error {
+	logger.Infof("starting '%s'...", ox.Label)
+
+	timer := time.NewTimer(ox.StartDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		logger.Infof("starting of '%s' has been interrupted.", ox.Label)
+		return ctx.Err()
+	}
+
+	if ox.StartError != nil {
+		return ox.StartError
+	}
+
+	logger.Infof("starting of '%s' has been completed.", ox.Label)
+	return nil
+}
+
 func (ox *Component) Dispose() error {
+	return ox.DisposeContext(context.Background())
+}
+
+func (ox *Component) DisposeContext(ctx context.Context) error {
 	logger.Infof("dispossing '%s'...", ox.Label)
 
-	time.Sleep(ox.DisposeDuration)
+	timer := time.NewTimer(ox.DisposeDuration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		logger.Infof("disposing of '%s' has been interrupted.", ox.Label)
+		return ctx.Err()
+	}
+
 	if ox.DisposeError != nil {
 		return ox.DisposeError
 	}
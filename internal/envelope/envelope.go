@@ -0,0 +1,96 @@
+package envelope
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RETRY_AFTER is the hint given to the clients of the unavailable responses,
+// e.g. the ones aborted by the shutdown.
+const RETRY_AFTER = 5 * time.Second
+
+// the keys of the messages in the i18n catalog.
+const (
+	MessageSuccess          = "success"
+	MessageInvalidRequest   = "invalid_request"
+	MessageNotFound         = "not_found"
+	MessageMethodNotAllowed = "method_not_allowed"
+	MessageUnavailable      = "service_unavailable"
+	MessageInternalError    = "internal_error"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest   Code = "INVALID_REQUEST"
+	CodeNotFound         Code = "NOT_FOUND"
+	CodeMethodNotAllowed Code = "METHOD_NOT_ALLOWED"
+	CodeUnavailable      Code = "SERVICE_UNAVAILABLE"
+	CodeInternal         Code = "INTERNAL_ERROR"
+)
+
+// CodeOf maps the http status of an error to its code.
+func CodeOf(status int) Code {
+	switch {
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case status == http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case status >= 400 && status < 500:
+		return CodeInvalidRequest
+	default:
+		return CodeInternal
+	}
+}
+
+// MessageOf maps the http status of an error to the key of its message.
+func MessageOf(status int) string {
+	switch {
+	case status == http.StatusNotFound:
+		return MessageNotFound
+	case status == http.StatusMethodNotAllowed:
+		return MessageMethodNotAllowed
+	case status == http.StatusServiceUnavailable:
+		return MessageUnavailable
+	case status >= 400 && status < 500:
+		return MessageInvalidRequest
+	default:
+		return MessageInternalError
+	}
+}
+
+// Body is the envelope of both routers, the message is resolved to the
+// language negotiated from Accept-Language. Code is only set on errors, and
+// RetryAfter on the unavailable ones.
+type Body struct {
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data,omitempty"`
+	Code       Code        `json:"code,omitempty"`
+	RequestID  string      `json:"request_id,omitempty"`
+	RetryAfter int         `json:"retry_after,omitempty"`
+}
+
+// Failure is the body of an error response of the status, the unavailable
+// ones carry the retry hint of the Retry-After header as well, for the routers
+// that can't set it.
+func Failure(status int, message string, data interface{}, requestID string) Body {
+	body := Body{
+		Message:   message,
+		Data:      data,
+		Code:      CodeOf(status),
+		RequestID: requestID,
+	}
+	if status == http.StatusServiceUnavailable {
+		body.RetryAfter = int(RETRY_AFTER / time.Second)
+	}
+
+	return body
+}
+
+// RetryAfterSeconds is the value of the Retry-After header.
+func RetryAfterSeconds() string {
+	return strconv.Itoa(int(RETRY_AFTER / time.Second))
+}
@@ -0,0 +1,58 @@
+package envelope
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFailure(t *testing.T) {
+	tests := []struct {
+		status         int
+		wantCode       Code
+		wantRetryAfter int
+	}{
+		{status: 400, wantCode: CodeInvalidRequest},
+		{status: 404, wantCode: CodeNotFound},
+		{status: 405, wantCode: CodeMethodNotAllowed},
+		{status: 500, wantCode: CodeInternal},
+		{status: 503, wantCode: CodeUnavailable, wantRetryAfter: 5},
+	}
+
+	for _, tt := range tests {
+		body := Failure(tt.status, "message", nil, "id")
+
+		if body.Code != tt.wantCode {
+			t.Errorf("code of %d = %q, want %q", tt.status, body.Code, tt.wantCode)
+		}
+
+		if body.RetryAfter != tt.wantRetryAfter {
+			t.Errorf("retry after of %d = %d, want %d", tt.status, body.RetryAfter, tt.wantRetryAfter)
+		}
+
+		if body.Message != "message" || body.RequestID != "id" {
+			t.Errorf("body of %d = %+v", tt.status, body)
+		}
+	}
+}
+
+func TestRequestIDOf(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(REQUEST_ID_HEADER, "abc")
+	if got := RequestIDOf(r); got != "abc" {
+		t.Errorf("request id = %q, want the one sent by the client", got)
+	}
+
+	if got := RequestIDOf(httptest.NewRequest("GET", "/", nil)); len(got) != 32 {
+		t.Errorf("request id = %q, want a new one", got)
+	}
+
+	if got := RequestIDOf(nil); got == "" {
+		t.Error("request id of no request is empty")
+	}
+
+	ctx := ContextWithRequestID(context.Background(), "abc")
+	if got := RequestID(ctx); got != "abc" {
+		t.Errorf("request id of the context = %q, want %q", got, "abc")
+	}
+}
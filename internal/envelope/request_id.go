@@ -0,0 +1,52 @@
+package envelope
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const REQUEST_ID_HEADER = "X-Request-ID"
+
+type requestIDKey struct{}
+
+// WithRequestID keeps the request ID sent by the client or generates a new
+// one, then exposes it through the context and the response header.
+func WithRequestID(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := RequestIDOf(r)
+		w.Header().Set(REQUEST_ID_HEADER, requestID)
+		handler.ServeHTTP(w, r.WithContext(ContextWithRequestID(r.Context(), requestID)))
+	})
+}
+
+// RequestIDOf returns the request ID sent by the client, or a new one when
+// there's none.
+func RequestIDOf(r *http.Request) string {
+	if r != nil {
+		if requestID := r.Header.Get(REQUEST_ID_HEADER); requestID != "" {
+			return requestID
+		}
+	}
+
+	return NewRequestID()
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func NewRequestID() string {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(buf[:])
+}
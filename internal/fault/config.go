@@ -0,0 +1,106 @@
+package fault
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadInjector reads the rules from the file pointed by FAULT_CONFIG_FILE
+// when it's set, the default rules are applied after them.
+func LoadInjector(defaults ...Rule) (*Injector, error) {
+	var rules []Rule
+	if path := os.Getenv("FAULT_CONFIG_FILE"); path != "" {
+		fileRules, err := LoadRulesFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		rules = fileRules
+	}
+
+	return NewInjector(append(rules, defaults...)...)
+}
+
+// LoadRulesFile reads the rules from a JSON or YAML file, the format is picked
+// from the file extension. The latencies are written as durations, e.g.
+// "500ms".
+func LoadRulesFile(path string) ([]Rule, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the fault config file: %w", err)
+	}
+
+	var payload struct {
+		Rules []struct {
+			Name         string  `json:"name" yaml:"name"`
+			Method       string  `json:"method" yaml:"method"`
+			Route        string  `json:"route" yaml:"route"`
+			Query        string  `json:"query" yaml:"query"`
+			Probability  float64 `json:"probability" yaml:"probability"`
+			Distribution string  `json:"distribution" yaml:"distribution"`
+			Latency      string  `json:"latency" yaml:"latency"`
+			LatencyMax   string  `json:"latency_max" yaml:"latency_max"`
+			ErrorStatus  int     `json:"error_status" yaml:"error_status"`
+			StoreFailure bool    `json:"store_failure" yaml:"store_failure"`
+		} `json:"rules" yaml:"rules"`
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(raw, &payload)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &payload)
+	default:
+		return nil, fmt.Errorf("unsupported fault config file format '%s'", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the fault config file: %w", err)
+	}
+
+	rules := make([]Rule, 0, len(payload.Rules))
+	for i, r := range payload.Rules {
+		rule := Rule{
+			Name:         r.Name,
+			Method:       strings.ToUpper(r.Method),
+			Route:        r.Route,
+			Query:        r.Query,
+			Probability:  r.Probability,
+			Distribution: Distribution(r.Distribution),
+			ErrorStatus:  r.ErrorStatus,
+			StoreFailure: r.StoreFailure,
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule-%d", i+1)
+		}
+
+		durations := []struct {
+			field string
+			raw   string
+			value *time.Duration
+		}{
+			{"latency", r.Latency, &rule.Latency},
+			{"latency_max", r.LatencyMax, &rule.LatencyMax},
+		}
+		for _, d := range durations {
+			if d.raw == "" {
+				continue
+			}
+
+			value, err := time.ParseDuration(d.raw)
+			if err != nil {
+				return nil, fmt.Errorf("fault rule '%s': %s '%s' is not a duration", rule.Name, d.field, d.raw)
+			}
+			*d.value = value
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
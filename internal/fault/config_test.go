@@ -0,0 +1,116 @@
+package fault
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    []Rule
+		wantErr bool
+	}{
+		{
+			name: "json",
+			file: "fault.json",
+			content: `{"rules": [
+				{"name": "flaky", "method": "get", "route": "/kv/{key}", "probability": 0.5, "error_status": 503},
+				{"query": "slow", "distribution": "uniform", "latency": "100ms", "latency_max": "1s"}
+			]}`,
+			want: []Rule{
+				{Name: "flaky", Method: "GET", Route: "/kv/{key}", Probability: 0.5, ErrorStatus: 503},
+				{Name: "rule-2", Query: "slow", Distribution: DistributionUniform, Latency: 100 * time.Millisecond, LatencyMax: time.Second},
+			},
+		},
+		{
+			name: "yaml",
+			file: "fault.yaml",
+			content: `rules:
+  - name: broken-redis
+    route: /kv
+    store_failure: true
+    latency: 2s
+`,
+			want: []Rule{
+				{Name: "broken-redis", Route: "/kv", StoreFailure: true, Latency: 2 * time.Second},
+			},
+		},
+		{
+			name:    "no rules",
+			file:    "fault.yml",
+			content: `rules: []`,
+			want:    []Rule{},
+		},
+		{
+			name:    "invalid duration",
+			file:    "fault.json",
+			content: `{"rules": [{"latency": "soon"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			file:    "fault.json",
+			content: `{"rules": [`,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported format",
+			file:    "fault.toml",
+			content: `rules = []`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := LoadRulesFile(writeFile(t, tt.file, tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(rules, tt.want) {
+				t.Errorf("rules = %+v, want %+v", rules, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadInjector(t *testing.T) {
+	t.Setenv("FAULT_CONFIG_FILE", writeFile(t, "fault.json", `{"rules": [{"name": "broken", "error_status": 500}]}`))
+
+	injector, err := LoadInjector(SlowRule(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rules of the file come before the defaults.
+	var names []string
+	for _, rule := range injector.rules {
+		names = append(names, rule.Name)
+	}
+	if want := []string{"broken", "slow"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rules = %v, want %v", names, want)
+	}
+
+	t.Setenv("FAULT_CONFIG_FILE", writeFile(t, "fault.json", `{"rules": [{"name": "invalid", "error_status": 200}]}`))
+	if _, err := LoadInjector(); err == nil {
+		t.Error("loaded a rule injecting a non error status")
+	}
+}
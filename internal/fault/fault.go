@@ -0,0 +1,206 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrStoreFailure = errors.New("simulated redis failure")
+
+// InjectedError is the error injected by a rule, it's answered with the
+// status of the rule.
+type InjectedError struct {
+	Rule   string
+	Status int
+}
+
+func (ox *InjectedError) Error() string {
+	return fmt.Sprintf("fault '%s' injected status %d", ox.Rule, ox.Status)
+}
+
+func (ox *InjectedError) StatusCode() int {
+	return ox.Status
+}
+
+type Distribution string
+
+const (
+	DistributionFixed   Distribution = "fixed"
+	DistributionUniform Distribution = "uniform"
+)
+
+// Rule injects faults into the requests it matches. The empty Method and
+// Route match every request, while Query only matches the requests having
+// that query param set to true. Probability is the chance of a matched
+// request getting the faults, zero means always.
+//
+// The latency is Latency on the fixed distribution, or picked between
+// Latency and LatencyMax on the uniform one. Then the request either fails
+// with ErrorStatus, or runs with every store operation failing when
+// StoreFailure is set.
+type Rule struct {
+	Name        string
+	Method      string
+	Route       string
+	Query       string
+	Probability float64
+
+	Distribution Distribution
+	Latency      time.Duration
+	LatencyMax   time.Duration
+
+	ErrorStatus  int
+	StoreFailure bool
+}
+
+// SlowRule keeps the behavior of the former "?slow=true" param, but the
+// delay is aborted once the request is cancelled.
+func SlowRule(latency time.Duration) Rule {
+	return Rule{
+		Name:         "slow",
+		Query:        "slow",
+		Distribution: DistributionFixed,
+		Latency:      latency,
+	}
+}
+
+func (ox Rule) matches(method string, route string, query func(name string) string) bool {
+	if ox.Method != "" && ox.Method != method {
+		return false
+	}
+
+	if ox.Route != "" && ox.Route != route {
+		return false
+	}
+
+	if ox.Query != "" {
+		enabled, err := strconv.ParseBool(query(ox.Query))
+		if err != nil || !enabled {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (ox Rule) validate() error {
+	switch ox.Distribution {
+	case "", DistributionFixed:
+	case DistributionUniform:
+		if ox.LatencyMax < ox.Latency {
+			return fmt.Errorf("fault rule '%s': latency_max must not be less than latency", ox.Name)
+		}
+	default:
+		return fmt.Errorf("fault rule '%s': unknown distribution '%s'", ox.Name, ox.Distribution)
+	}
+
+	if ox.Latency < 0 {
+		return fmt.Errorf("fault rule '%s': latency must not be negative", ox.Name)
+	}
+
+	if ox.Probability < 0 || ox.Probability > 1 {
+		return fmt.Errorf("fault rule '%s': probability must be between 0 and 1", ox.Name)
+	}
+
+	if ox.ErrorStatus != 0 && (ox.ErrorStatus < 400 || ox.ErrorStatus > 599) {
+		return fmt.Errorf("fault rule '%s': error status %d is not an error", ox.Name, ox.ErrorStatus)
+	}
+
+	return nil
+}
+
+// Injector applies the first rule matching the request.
+type Injector struct {
+	rules []Rule
+
+	mu     sync.Mutex
+	random *rand.Rand
+}
+
+func NewInjector(rules ...Rule) (*Injector, error) {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Injector{
+		rules:  rules,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// Inject applies the faults of the matching rule. The latency is waited
+// under the context, so a cancelled request or server stops it right away
+// with the error of the context. The returned context carries the store
+// failure, see Store.
+func (ox *Injector) Inject(ctx context.Context, method string, route string, query func(name string) string) (context.Context, error) {
+	rule, ok := ox.match(method, route, query)
+	if !ok {
+		return ctx, nil
+	}
+
+	if latency := ox.latency(rule); latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx, ctx.Err()
+		}
+	}
+
+	if rule.ErrorStatus != 0 {
+		return ctx, &InjectedError{
+			Rule:   rule.Name,
+			Status: rule.ErrorStatus,
+		}
+	}
+
+	if rule.StoreFailure {
+		ctx = context.WithValue(ctx, storeFailureKey{}, rule.Name)
+	}
+
+	return ctx, nil
+}
+
+func (ox *Injector) match(method string, route string, query func(name string) string) (Rule, bool) {
+	for _, rule := range ox.rules {
+		if !rule.matches(method, route, query) {
+			continue
+		}
+
+		// a missed chance leaves the request to the next rules.
+		if rule.Probability > 0 && rule.Probability < 1 && ox.float64() >= rule.Probability {
+			continue
+		}
+
+		return rule, true
+	}
+
+	return Rule{}, false
+}
+
+func (ox *Injector) latency(rule Rule) time.Duration {
+	if rule.Distribution != DistributionUniform || rule.LatencyMax == rule.Latency {
+		return rule.Latency
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return rule.Latency + time.Duration(ox.random.Int63n(int64(rule.LatencyMax-rule.Latency)))
+}
+
+func (ox *Injector) float64() float64 {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return ox.random.Float64()
+}
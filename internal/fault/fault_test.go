@@ -0,0 +1,199 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// query returns the query params of a request.
+func query(params map[string]string) func(name string) string {
+	return func(name string) string {
+		return params[name]
+	}
+}
+
+func newInjector(t *testing.T, rules ...Rule) *Injector {
+	t.Helper()
+
+	injector, err := NewInjector(rules...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a fixed seed keeps the rates reproducible.
+	injector.random = rand.New(rand.NewSource(1))
+	return injector
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   Rule
+		method string
+		route  string
+		params map[string]string
+		want   bool
+	}{
+		{name: "any request", rule: Rule{}, method: "GET", route: "/kv", want: true},
+		{name: "same method", rule: Rule{Method: "PUT"}, method: "PUT", route: "/kv/{key}", want: true},
+		{name: "other method", rule: Rule{Method: "PUT"}, method: "GET", route: "/kv/{key}", want: false},
+		{name: "other route", rule: Rule{Route: "/kv"}, method: "GET", route: "/kv/{key}", want: false},
+		{name: "query enabled", rule: Rule{Query: "slow"}, method: "GET", route: "/kv", params: map[string]string{"slow": "true"}, want: true},
+		{name: "query disabled", rule: Rule{Query: "slow"}, method: "GET", route: "/kv", params: map[string]string{"slow": "false"}, want: false},
+		{name: "query missing", rule: Rule{Query: "slow"}, method: "GET", route: "/kv", want: false},
+		{name: "query invalid", rule: Rule{Query: "slow"}, method: "GET", route: "/kv", params: map[string]string{"slow": "yes please"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule.matches(tt.method, tt.route, query(tt.params)); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "fixed", rule: Rule{Latency: time.Second}},
+		{name: "uniform", rule: Rule{Distribution: DistributionUniform, Latency: time.Second, LatencyMax: 2 * time.Second}},
+		{name: "uniform upside down", rule: Rule{Distribution: DistributionUniform, Latency: 2 * time.Second, LatencyMax: time.Second}, wantErr: true},
+		{name: "unknown distribution", rule: Rule{Distribution: "normal"}, wantErr: true},
+		{name: "negative latency", rule: Rule{Latency: -time.Second}, wantErr: true},
+		{name: "probability above one", rule: Rule{Probability: 1.5}, wantErr: true},
+		{name: "negative probability", rule: Rule{Probability: -0.5}, wantErr: true},
+		{name: "error status", rule: Rule{ErrorStatus: 503}},
+		{name: "success status", rule: Rule{ErrorStatus: 200}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInject(t *testing.T) {
+	tests := []struct {
+		name             string
+		rule             Rule
+		params           map[string]string
+		wantLatency      time.Duration
+		wantStatus       int
+		wantStoreFailure bool
+	}{
+		{name: "disabled", rule: Rule{Query: "slow", Latency: 50 * time.Millisecond, ErrorStatus: 500}},
+		{name: "latency", rule: Rule{Query: "slow", Latency: 50 * time.Millisecond}, params: map[string]string{"slow": "true"}, wantLatency: 50 * time.Millisecond},
+		{name: "error", rule: Rule{ErrorStatus: 502}, wantStatus: 502},
+		{name: "latency then error", rule: Rule{Latency: 50 * time.Millisecond, ErrorStatus: 500}, wantLatency: 50 * time.Millisecond, wantStatus: 500},
+		{name: "store failure", rule: Rule{StoreFailure: true}, wantStoreFailure: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := newInjector(t, tt.rule)
+
+			startedAt := time.Now()
+			ctx, err := injector.Inject(context.Background(), "GET", "/kv", query(tt.params))
+			elapsed := time.Since(startedAt)
+
+			if elapsed < tt.wantLatency || elapsed > tt.wantLatency+tolerance {
+				t.Errorf("latency = %s, want %s", elapsed, tt.wantLatency)
+			}
+
+			var injected *InjectedError
+			switch {
+			case tt.wantStatus == 0 && err != nil:
+				t.Errorf("injected %v, want nothing", err)
+			case tt.wantStatus != 0 && (!errors.As(err, &injected) || injected.StatusCode() != tt.wantStatus):
+				t.Errorf("injected %v, want status %d", err, tt.wantStatus)
+			}
+
+			if failed := storeFailure(ctx) != nil; failed != tt.wantStoreFailure {
+				t.Errorf("store failure = %v, want %v", failed, tt.wantStoreFailure)
+			}
+		})
+	}
+}
+
+// tolerance absorbs the scheduling delay of the injected latency.
+const tolerance = 30 * time.Millisecond
+
+func TestInjectCancelled(t *testing.T) {
+	injector := newInjector(t, Rule{Latency: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	startedAt := time.Now()
+	if _, err := injector.Inject(ctx, "GET", "/kv", query(nil)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if elapsed := time.Since(startedAt); elapsed > 20*time.Millisecond+tolerance {
+		t.Errorf("the latency wasn't aborted, took %s", elapsed)
+	}
+}
+
+func TestInjectUniformLatency(t *testing.T) {
+	rule := Rule{Distribution: DistributionUniform, Latency: 10 * time.Millisecond, LatencyMax: 20 * time.Millisecond}
+	injector := newInjector(t, rule)
+
+	for i := 0; i < 1000; i++ {
+		if latency := injector.latency(rule); latency < rule.Latency || latency >= rule.LatencyMax {
+			t.Fatalf("latency = %s, want between %s and %s", latency, rule.Latency, rule.LatencyMax)
+		}
+	}
+}
+
+func TestInjectorProbability(t *testing.T) {
+	const requests = 10000
+
+	tests := []struct {
+		probability float64
+		want        float64
+	}{
+		{probability: 0, want: 1},
+		{probability: 0.1, want: 0.1},
+		{probability: 0.5, want: 0.5},
+		{probability: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		injector := newInjector(t, Rule{Probability: tt.probability, ErrorStatus: 500})
+
+		injected := 0
+		for i := 0; i < requests; i++ {
+			if _, err := injector.Inject(context.Background(), "GET", "/kv", query(nil)); err != nil {
+				injected++
+			}
+		}
+
+		if rate := float64(injected) / requests; rate < tt.want-0.02 || rate > tt.want+0.02 {
+			t.Errorf("rate of probability %v = %v, want %v", tt.probability, rate, tt.want)
+		}
+	}
+}
+
+func TestInjectorMissedChanceFallsThrough(t *testing.T) {
+	injector := newInjector(t,
+		Rule{Name: "never", Probability: 0.000001, ErrorStatus: 500},
+		Rule{Name: "always", ErrorStatus: 503},
+	)
+
+	_, err := injector.Inject(context.Background(), "GET", "/kv", query(nil))
+
+	var injected *InjectedError
+	if !errors.As(err, &injected) || injected.Rule != "always" {
+		t.Errorf("injected %v, want the next rule", err)
+	}
+}
@@ -0,0 +1,65 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+)
+
+type storeFailureKey struct{}
+
+// failingStore fails every operation of the requests marked by a rule with
+// StoreFailure, the rest pass through.
+type failingStore struct {
+	store kvstore.KeyValueStore
+}
+
+// Store wraps the store, so the rules can simulate a redis failure.
+func Store(store kvstore.KeyValueStore) kvstore.KeyValueStore {
+	return &failingStore{
+		store: store,
+	}
+}
+
+func (ox *failingStore) Get(ctx context.Context, key string) (string, error) {
+	if err := storeFailure(ctx); err != nil {
+		return "", err
+	}
+
+	return ox.store.Get(ctx, key)
+}
+
+func (ox *failingStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := storeFailure(ctx); err != nil {
+		return err
+	}
+
+	return ox.store.Set(ctx, key, value, ttl)
+}
+
+func (ox *failingStore) Delete(ctx context.Context, key string) error {
+	if err := storeFailure(ctx); err != nil {
+		return err
+	}
+
+	return ox.store.Delete(ctx, key)
+}
+
+func (ox *failingStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if err := storeFailure(ctx); err != nil {
+		return nil, err
+	}
+
+	return ox.store.Keys(ctx, prefix)
+}
+
+func storeFailure(ctx context.Context) error {
+	rule, ok := ctx.Value(storeFailureKey{}).(string)
+	if !ok {
+		return nil
+	}
+
+	return fmt.Errorf("fault '%s': %w", rule, ErrStoreFailure)
+}
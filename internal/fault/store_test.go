@@ -0,0 +1,58 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+)
+
+func TestStore(t *testing.T) {
+	memory := kvstore.NewMemoryStore()
+	if err := memory.Set(context.Background(), "user:1", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+	store := Store(memory)
+
+	injector := newInjector(t, Rule{Name: "broken-redis", Query: "broken", StoreFailure: true})
+	healthyCtx, err := injector.Inject(context.Background(), "GET", "/kv", query(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	brokenCtx, err := injector.Inject(context.Background(), "GET", "/kv", query(map[string]string{"broken": "true"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := store.Get(healthyCtx, "user:1"); err != nil || value != "alice" {
+		t.Errorf("got %q, %v, want %q", value, err, "alice")
+	}
+
+	operations := map[string]func(ctx context.Context) error{
+		"get": func(ctx context.Context) error {
+			_, err := store.Get(ctx, "user:1")
+			return err
+		},
+		"set": func(ctx context.Context) error {
+			return store.Set(ctx, "user:1", "bob", 0)
+		},
+		"delete": func(ctx context.Context) error {
+			return store.Delete(ctx, "user:1")
+		},
+		"keys": func(ctx context.Context) error {
+			_, err := store.Keys(ctx, "user:")
+			return err
+		},
+	}
+	for name, operation := range operations {
+		if err := operation(brokenCtx); !errors.Is(err, ErrStoreFailure) {
+			t.Errorf("%s = %v, want %v", name, err, ErrStoreFailure)
+		}
+	}
+
+	// the failed operations never reached the store.
+	if value, err := memory.Get(context.Background(), "user:1"); err != nil || value != "alice" {
+		t.Errorf("got %q, %v, want %q", value, err, "alice")
+	}
+}
@@ -0,0 +1,128 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+const CHECK_TIMEOUT = time.Second
+
+type CheckFunc func(ctx context.Context) error
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker serves the liveness and readiness probes. The readiness turns
+// unavailable as soon as the draining begins, so the load balancer stops
+// routing new requests before the servers are shut down.
+type Checker struct {
+	// PreStopDelay is how long Drain waits after flipping the readiness, to
+	// give the load balancer time to drop the instance.
+	PreStopDelay time.Duration
+
+	mu       sync.RWMutex
+	checks   []check
+	draining int32
+}
+
+func NewChecker(preStopDelay time.Duration) *Checker {
+	return &Checker{
+		PreStopDelay: preStopDelay,
+	}
+}
+
+// AddReadinessCheck registers a dependency check, the instance is only ready
+// when every check passes.
+func (ox *Checker) AddReadinessCheck(name string, fn CheckFunc) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.checks = append(ox.checks, check{
+		name: name,
+		fn:   fn,
+	})
+}
+
+func (ox *Checker) IsDraining() bool {
+	return atomic.LoadInt32(&ox.draining) == 1
+}
+
+// Drain marks the instance as not ready, then waits for the pre-stop delay
+// or until the context is done.
+func (ox *Checker) Drain(ctx context.Context) {
+	if atomic.CompareAndSwapInt32(&ox.draining, 0, 1) {
+		logger.Infof("health: draining, readiness is now unavailable.")
+	}
+
+	if ox.PreStopDelay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(ox.PreStopDelay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+func (ox *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusOK, "ok", nil)
+	})
+}
+
+func (ox *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ox.IsDraining() {
+			writeStatus(w, http.StatusServiceUnavailable, "draining", nil)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), CHECK_TIMEOUT)
+		defer cancel()
+
+		ox.mu.RLock()
+		checks := ox.checks
+		ox.mu.RUnlock()
+
+		code, status := http.StatusOK, "ok"
+		results := make(map[string]string, len(checks))
+		for _, c := range checks {
+			if err := c.fn(ctx); err != nil {
+				code, status = http.StatusServiceUnavailable, "unavailable"
+				results[c.name] = err.Error()
+				continue
+			}
+
+			results[c.name] = "ok"
+		}
+
+		writeStatus(w, code, status, results)
+	})
+}
+
+func writeStatus(w http.ResponseWriter, code int, status string, checks map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}{
+		Status: status,
+		Checks: checks,
+	})
+	if err != nil {
+		logger.Errf("health: failed to write the status: %+v", err)
+	}
+}
@@ -5,59 +5,154 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"time"
+	"strings"
 
-	"github.com/go-redis/redis/v8"
-	"github.com/koinworks/asgard-heimdal/utils/utinterface"
+	"github.com/luthfikw/example.graceful-shutdown/internal/envelope"
+	"github.com/luthfikw/example.graceful-shutdown/internal/fault"
+	"github.com/luthfikw/example.graceful-shutdown/internal/health"
+	"github.com/luthfikw/example.graceful-shutdown/internal/i18n"
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvapi"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/metrics"
 )
 
-func NewHTTPServerMux(label string, apiDuration time.Duration, redisClient *redis.Client) http.Handler {
+func NewHTTPServerMux(label string, injector *fault.Injector, store kvstore.KeyValueStore, storeConfig *kvstore.Config, healthChecker *health.Checker, tracker *inflight.Tracker, catalog *i18n.Catalog, appMetrics *metrics.Metrics, appLifecycle *lifecycle.Lifecycle) http.Handler {
+	api := kvapi.New(fault.Store(store), storeConfig)
+	respond := &responder{catalog: catalog}
+
 	var serverMux http.ServeMux
-	serverMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		fmt.Printf("server '%s' got the request...\n", label)
-		defer func() {
-			fmt.Printf("server '%s' complete the request.\n", label)
-		}()
+	serverMux.Handle("/healthz", healthChecker.LivenessHandler())
+	serverMux.Handle("/readyz", healthChecker.ReadinessHandler())
 
-		isSlow := utinterface.ToBool(r.URL.Query().Get("slow"), false)
-		if isSlow {
-			time.Sleep(apiDuration)
+	serverMux.Handle("/kv", tracker.Track("/kv", appMetrics.Instrument("/kv", handle(label, "/kv", appLifecycle, injector, respond, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" {
+			w.Header().Set("Allow", "GET")
+			respond.failure(w, r, 405, nil)
+			return
 		}
 
+		result, err := api.List(r.Context(), r.URL.Query().Get("prefix"))
+		if err != nil {
+			respond.error(w, r, err)
+			return
+		}
+
+		respond.success(w, r, result)
+	}))))
+
+	serverMux.Handle("/kv/", tracker.Track("/kv/{key}", appMetrics.Instrument("/kv/{key}", handle(label, "/kv/{key}", appLifecycle, injector, respond, func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/kv/")
+
 		switch r.Method {
 		case "GET":
-			result := redisClient.Get(r.Context(), "test")
-			if err := result.Err(); err != nil {
-				log.Println(err)
-				w.WriteHeader(500)
+			result, err := api.Get(r.Context(), key)
+			if err != nil {
+				respond.error(w, r, err)
 				return
 			}
 
-			w.WriteHeader(200)
-			fmt.Fprintf(w, "Value: %s", result.Val())
+			respond.success(w, r, result)
 
-		case "POST":
-			var payload struct {
-				Value string `json:"value"`
-			}
+		case "PUT":
+			var payload kvapi.PutRequest
 			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-				w.WriteHeader(400)
+				respond.failure(w, r, 400, nil)
 				return
 			}
 
-			result := redisClient.Set(r.Context(), "test", payload.Value, time.Hour)
-			if err := result.Err(); err != nil {
-				log.Println(err)
-				w.WriteHeader(500)
+			result, err := api.Put(r.Context(), key, payload)
+			if err != nil {
+				respond.error(w, r, err)
 				return
 			}
 
-			w.WriteHeader(200)
+			respond.success(w, r, result)
+
+		case "DELETE":
+			result, err := api.Delete(r.Context(), key)
+			if err != nil {
+				respond.error(w, r, err)
+				return
+			}
+
+			respond.success(w, r, result)
 
 		default:
-			w.WriteHeader(404)
+			w.Header().Set("Allow", "GET, PUT, DELETE")
+			respond.failure(w, r, 405, nil)
+		}
+	}))))
+
+	return envelope.WithRequestID(&serverMux)
+}
+
+// handle rejects the request unless the lifecycle accepts new work, then
+// injects the faults of the route before running the handler, the injected
+// delay stops once the request is cancelled.
+func handle(label string, route string, appLifecycle *lifecycle.Lifecycle, injector *fault.Injector, respond *responder, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Printf("server '%s' got the request...\n", label)
+		defer func() {
+			fmt.Printf("server '%s' complete the request.\n", label)
+		}()
+
+		if err := appLifecycle.Accept(); err != nil {
+			respond.error(w, r, err)
+			return
+		}
+
+		ctx, err := injector.Inject(r.Context(), r.Method, route, r.URL.Query().Get)
+		if err != nil {
+			respond.error(w, r, err)
+			return
 		}
+
+		handler(w, r.WithContext(ctx))
 	})
+}
+
+// responder writes the responses in the envelope, with the message in the
+// language negotiated from the Accept-Language header.
+type responder struct {
+	catalog *i18n.Catalog
+}
+
+func (ox *responder) success(w http.ResponseWriter, r *http.Request, data interface{}) {
+	writeJSON(w, 200, envelope.Body{
+		Message:   ox.catalog.Localize(r.Header.Get("Accept-Language"), envelope.MessageSuccess, nil),
+		Data:      data,
+		RequestID: envelope.RequestID(r.Context()),
+	})
+}
+
+// error only exposes the detail of the client errors, the rest are logged.
+func (ox *responder) error(w http.ResponseWriter, r *http.Request, err error) {
+	status := kvapi.StatusCode(err)
+	if status >= 500 {
+		log.Printf("request '%s' failed: %s\n", envelope.RequestID(r.Context()), err)
+		ox.failure(w, r, status, nil)
+		return
+	}
+
+	ox.failure(w, r, status, err.Error())
+}
+
+func (ox *responder) failure(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	if status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", envelope.RetryAfterSeconds())
+	}
+
+	args := i18n.Args{"method": r.Method}
+	message := ox.catalog.Localize(r.Header.Get("Accept-Language"), envelope.MessageOf(status), args)
+	writeJSON(w, status, envelope.Failure(status, message, data, envelope.RequestID(r.Context())))
+}
 
-	return &serverMux
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Println(err)
+	}
 }
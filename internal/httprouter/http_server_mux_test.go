@@ -0,0 +1,187 @@
+package httprouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/envelope"
+	"github.com/luthfikw/example.graceful-shutdown/internal/fault"
+	"github.com/luthfikw/example.graceful-shutdown/internal/health"
+	"github.com/luthfikw/example.graceful-shutdown/internal/i18n"
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/iredis"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/metrics"
+)
+
+// drainingStore refuses every command the way the redis client does once it
+// starts closing.
+type drainingStore struct {
+	kvstore.KeyValueStore
+}
+
+func (ox drainingStore) Get(ctx context.Context, key string) (string, error) {
+	return "", iredis.ErrDraining
+}
+
+func newServerMux(t *testing.T, store kvstore.KeyValueStore, state lifecycle.State) http.Handler {
+	t.Helper()
+
+	injector, err := fault.NewInjector()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := i18n.LoadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the draining state is only reachable from the ready one.
+	appLifecycle := lifecycle.New()
+	path := []lifecycle.State{state}
+	if state == lifecycle.StateDraining {
+		path = []lifecycle.State{lifecycle.StateReady, state}
+	}
+	for _, to := range path {
+		if err := appLifecycle.Transition(to); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tracker := inflight.NewTracker()
+	return NewHTTPServerMux("test", injector, store, &kvstore.Config{TTL: time.Minute}, health.NewChecker(0), tracker, catalog, metrics.New(tracker), appLifecycle)
+}
+
+func newStore(t *testing.T) kvstore.KeyValueStore {
+	t.Helper()
+
+	store := kvstore.NewMemoryStore()
+	if err := store.Set(context.Background(), "user:1", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestServerMux(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      kvstore.KeyValueStore
+		state      lifecycle.State
+		method     string
+		target     string
+		body       string
+		wantStatus int
+		wantCode   envelope.Code
+	}{
+		{name: "get", method: "GET", target: "/kv/user:1", wantStatus: 200},
+		{name: "list", method: "GET", target: "/kv?prefix=user:", wantStatus: 200},
+		{name: "put", method: "PUT", target: "/kv/user:2", body: `{"value":"bob","ttl":"30s"}`, wantStatus: 200},
+		{name: "delete", method: "DELETE", target: "/kv/user:1", wantStatus: 200},
+		{name: "get a missing key", method: "GET", target: "/kv/user:2", wantStatus: 404, wantCode: envelope.CodeNotFound},
+		{name: "delete a missing key", method: "DELETE", target: "/kv/user:2", wantStatus: 404, wantCode: envelope.CodeNotFound},
+		{name: "empty key", method: "GET", target: "/kv/", wantStatus: 400, wantCode: envelope.CodeInvalidRequest},
+		{name: "nested key", method: "GET", target: "/kv/user/1", wantStatus: 400, wantCode: envelope.CodeInvalidRequest},
+		{name: "invalid payload", method: "PUT", target: "/kv/user:2", body: `{"value":`, wantStatus: 400, wantCode: envelope.CodeInvalidRequest},
+		{name: "invalid ttl", method: "PUT", target: "/kv/user:2", body: `{"value":"bob","ttl":"soon"}`, wantStatus: 400, wantCode: envelope.CodeInvalidRequest},
+		{name: "key method not allowed", method: "POST", target: "/kv/user:1", wantStatus: 405, wantCode: envelope.CodeMethodNotAllowed},
+		{name: "list method not allowed", method: "DELETE", target: "/kv", wantStatus: 405, wantCode: envelope.CodeMethodNotAllowed},
+		{name: "not ready", state: lifecycle.StateStarting, method: "GET", target: "/kv/user:1", wantStatus: 503, wantCode: envelope.CodeUnavailable},
+		{name: "draining", state: lifecycle.StateDraining, method: "GET", target: "/kv/user:1", wantStatus: 200},
+		{name: "stopping", state: lifecycle.StateStopping, method: "GET", target: "/kv/user:1", wantStatus: 503, wantCode: envelope.CodeUnavailable},
+		{name: "redis draining", store: drainingStore{}, method: "GET", target: "/kv/user:1", wantStatus: 503, wantCode: envelope.CodeUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, state := tt.store, tt.state
+			if store == nil {
+				store = newStore(t)
+			}
+			if state == "" {
+				state = lifecycle.StateReady
+			}
+
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			newServerMux(t, store, state).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			var body envelope.Body
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+
+			if body.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", body.Code, tt.wantCode)
+			}
+
+			if body.RequestID == "" {
+				t.Error("the request id is missing")
+			}
+
+			wantRetryAfter := ""
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				wantRetryAfter = envelope.RetryAfterSeconds()
+			}
+			if got := w.Header().Get("Retry-After"); got != wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, wantRetryAfter)
+			}
+			if got := strconv.Itoa(body.RetryAfter); wantRetryAfter != "" && got != wantRetryAfter {
+				t.Errorf("retry_after = %s, want %s", got, wantRetryAfter)
+			}
+
+			if tt.wantStatus == http.StatusMethodNotAllowed && w.Header().Get("Allow") == "" {
+				t.Error("the Allow header is missing")
+			}
+		})
+	}
+}
+
+func TestServerMuxInvalidPayload(t *testing.T) {
+	r := httptest.NewRequest("PUT", "/kv/user:2", strings.NewReader(`{"value":`))
+	w := httptest.NewRecorder()
+	newServerMux(t, newStore(t), lifecycle.StateReady).ServeHTTP(w, r)
+
+	var body envelope.Body
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	// the localized message is the only text, as on the bivrost router.
+	if body.Data != nil {
+		t.Errorf("data = %v, want none", body.Data)
+	}
+}
+
+func TestServerMuxLocalized(t *testing.T) {
+	r := httptest.NewRequest("GET", "/kv/user:2", nil)
+	r.Header.Set("Accept-Language", "id-ID,id;q=0.9")
+	w := httptest.NewRecorder()
+	newServerMux(t, newStore(t), lifecycle.StateReady).ServeHTTP(w, r)
+
+	var body envelope.Body
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := i18n.LoadCatalog()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := catalog.Localize("id", envelope.MessageNotFound, nil); body.Message != want {
+		t.Errorf("message = %q, want %q", body.Message, want)
+	}
+}
@@ -0,0 +1,299 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+	"gopkg.in/yaml.v3"
+)
+
+const DEFAULT_LANGUAGE = "en"
+
+//go:embed locales
+var embedded embed.FS
+
+// Args fills the "{name}" placeholders of a message.
+type Args map[string]interface{}
+
+// localeFile is a single language, named after the file (e.g. "id.yaml").
+// Fallback lists the languages tried before the default one when a message
+// is missing.
+type localeFile struct {
+	Fallback []string          `json:"fallback" yaml:"fallback"`
+	Messages map[string]string `json:"messages" yaml:"messages"`
+}
+
+// Catalog holds the messages of every language. A message is resolved
+// through the fallback chain of the language: the language itself, its base
+// (e.g. "id" of "id-id"), its configured fallbacks, then the default
+// language.
+type Catalog struct {
+	defaultLanguage string
+	messages        map[string]map[string]string
+	fallbacks       map[string][]string
+}
+
+func NewCatalog(defaultLanguage string) *Catalog {
+	return &Catalog{
+		defaultLanguage: normalize(defaultLanguage),
+		messages:        make(map[string]map[string]string),
+		fallbacks:       make(map[string][]string),
+	}
+}
+
+// LoadCatalog loads the embedded locales, then the ones in the directory
+// pointed by I18N_DIR when it's set, so languages can be added or overridden
+// without rebuilding.
+func LoadCatalog() (*Catalog, error) {
+	catalog := NewCatalog(DEFAULT_LANGUAGE)
+
+	if err := catalog.LoadFS(embedded, "locales"); err != nil {
+		return nil, err
+	}
+
+	if dir := os.Getenv("I18N_DIR"); dir != "" {
+		if err := catalog.LoadFS(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+
+	return catalog, nil
+}
+
+// LoadFS loads every .json, .yaml and .yml file of the directory, the
+// messages of a language that is already loaded are merged.
+func (ox *Catalog) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return fmt.Errorf("failed to read the locales: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		name := entry.Name()
+		ext := strings.ToLower(path.Ext(name))
+		if ext != ".json" && ext != ".yaml" && ext != ".yml" {
+			continue
+		}
+
+		raw, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("failed to read the locale '%s': %w", name, err)
+		}
+
+		var file localeFile
+		if ext == ".json" {
+			err = json.Unmarshal(raw, &file)
+		} else {
+			err = yaml.Unmarshal(raw, &file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse the locale '%s': %w", name, err)
+		}
+
+		ox.Add(strings.TrimSuffix(name, path.Ext(name)), file.Messages, file.Fallback...)
+	}
+
+	return nil
+}
+
+// Add merges the messages of the language, the fallbacks replace the
+// existing ones when given.
+func (ox *Catalog) Add(language string, messages map[string]string, fallbacks ...string) {
+	language = normalize(language)
+
+	if ox.messages[language] == nil {
+		ox.messages[language] = make(map[string]string)
+	}
+	for key, message := range messages {
+		ox.messages[language][key] = message
+	}
+
+	if len(fallbacks) > 0 {
+		chain := make([]string, 0, len(fallbacks))
+		for _, fallback := range fallbacks {
+			chain = append(chain, normalize(fallback))
+		}
+		ox.fallbacks[language] = chain
+	}
+}
+
+func (ox *Catalog) Languages() []string {
+	languages := make([]string, 0, len(ox.messages))
+	for language := range ox.messages {
+		languages = append(languages, language)
+	}
+
+	sort.Strings(languages)
+	return languages
+}
+
+// Translate resolves the message through the fallback chain of the language,
+// the key itself is returned when no language has it.
+func (ox *Catalog) Translate(language string, key string, args Args) string {
+	for _, candidate := range ox.chain(language) {
+		if message, ok := ox.messages[candidate][key]; ok {
+			return format(message, args)
+		}
+	}
+
+	return key
+}
+
+// Localize translates the message into the most preferred language of the
+// Accept-Language header that the catalog knows.
+func (ox *Catalog) Localize(acceptLanguage string, key string, args Args) string {
+	return ox.Translate(ox.Negotiate(acceptLanguage), key, args)
+}
+
+// Negotiate picks the most preferred language of the Accept-Language header
+// that the catalog knows, or the default language.
+func (ox *Catalog) Negotiate(acceptLanguage string) string {
+	for _, language := range ParseAcceptLanguage(acceptLanguage) {
+		if _, ok := ox.messages[language]; ok {
+			return language
+		}
+
+		if _, ok := ox.messages[base(language)]; ok {
+			return base(language)
+		}
+	}
+
+	return ox.defaultLanguage
+}
+
+// Missing lists the keys every language lacks compared to the union of the
+// keys of all languages, the fallbacks are not taken into account.
+func (ox *Catalog) Missing() map[string][]string {
+	keys := make(map[string]struct{})
+	for _, messages := range ox.messages {
+		for key := range messages {
+			keys[key] = struct{}{}
+		}
+	}
+
+	missing := make(map[string][]string)
+	for language, messages := range ox.messages {
+		for key := range keys {
+			if _, ok := messages[key]; !ok {
+				missing[language] = append(missing[language], key)
+			}
+		}
+
+		sort.Strings(missing[language])
+	}
+
+	return missing
+}
+
+// LogMissing reports the missing translations, meant to be called once at
+// startup.
+func (ox *Catalog) LogMissing() {
+	missing := ox.Missing()
+	for _, language := range ox.Languages() {
+		if keys := missing[language]; len(keys) > 0 {
+			logger.Infof("i18n: language '%s' is missing %d translation(s): %s.", language, len(keys), strings.Join(keys, ", "))
+		}
+	}
+}
+
+func (ox *Catalog) chain(language string) []string {
+	language = normalize(language)
+
+	chain := []string{language}
+	if b := base(language); b != language {
+		chain = append(chain, b)
+	}
+	chain = append(chain, ox.fallbacks[language]...)
+	chain = append(chain, ox.fallbacks[base(language)]...)
+	chain = append(chain, ox.defaultLanguage)
+
+	return chain
+}
+
+// ParseAcceptLanguage returns the languages of the header ordered by their
+// quality, the ones with zero quality and the wildcard are left out.
+func ParseAcceptLanguage(header string) []string {
+	type languageRange struct {
+		language string
+		quality  float64
+	}
+
+	ranges := make([]languageRange, 0)
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		language := normalize(fields[0])
+		if language == "" || language == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, languageRange{
+			language: language,
+			quality:  quality,
+		})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	languages := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		languages = append(languages, r.language)
+	}
+
+	return languages
+}
+
+func format(message string, args Args) string {
+	if len(args) == 0 {
+		return message
+	}
+
+	pairs := make([]string, 0, len(args)*2)
+	for name, value := range args {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+func normalize(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+func base(language string) string {
+	if i := strings.IndexByte(language, '-'); i > 0 {
+		return language[:i]
+	}
+
+	return language
+}
@@ -0,0 +1,140 @@
+package inflight
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+const PROGRESS_INTERVAL = time.Second
+
+type Request struct {
+	ID        uint64
+	Route     string
+	Method    string
+	StartedAt time.Time
+}
+
+// Tracker keeps track of the in-flight requests, so the draining progress can
+// be observed while the servers are being shut down.
+type Tracker struct {
+	mu       sync.Mutex
+	nextID   uint64
+	requests map[uint64]Request
+	routes   map[string]int
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{
+		requests: make(map[uint64]Request),
+		routes:   make(map[string]int),
+	}
+}
+
+// Begin marks a request to the route as in-flight until the returned func is
+// called.
+func (ox *Tracker) Begin(method string, route string) (done func()) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.nextID++
+	id := ox.nextID
+	ox.requests[id] = Request{
+		ID:        id,
+		Route:     route,
+		Method:    method,
+		StartedAt: time.Now(),
+	}
+	ox.routes[route]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ox.mu.Lock()
+			defer ox.mu.Unlock()
+
+			delete(ox.requests, id)
+			ox.routes[route]--
+			if ox.routes[route] <= 0 {
+				delete(ox.routes, route)
+			}
+		})
+	}
+}
+
+// Track wraps the handler of the route to count its in-flight requests.
+func (ox *Tracker) Track(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		done := ox.Begin(r.Method, route)
+		defer done()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (ox *Tracker) Count() int {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return len(ox.requests)
+}
+
+func (ox *Tracker) CountByRoute() map[string]int {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	counts := make(map[string]int, len(ox.routes))
+	for route, count := range ox.routes {
+		counts[route] = count
+	}
+
+	return counts
+}
+
+// Snapshot returns the in-flight requests, the oldest first.
+func (ox *Tracker) Snapshot() []Request {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	requests := make([]Request, 0, len(ox.requests))
+	for _, req := range ox.requests {
+		requests = append(requests, req)
+	}
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].ID < requests[j].ID
+	})
+
+	return requests
+}
+
+// Watch logs the draining progress every second until there are no more
+// in-flight requests, or lists the remaining ones once the context is done.
+// It's meant to run alongside the server shutdown.
+func (ox *Tracker) Watch(ctx context.Context) {
+	ticker := time.NewTicker(PROGRESS_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		count := ox.Count()
+		if count == 0 {
+			logger.Infof("inflight: all requests have been drained.")
+			return
+		}
+
+		select {
+		case <-ticker.C:
+			logger.Infof("inflight: waiting for %d request(s) to complete, %+v", count, ox.CountByRoute())
+
+		case <-ctx.Done():
+			for _, req := range ox.Snapshot() {
+				logger.Errf("inflight: request #%d '%s %s' is still running after %s", req.ID, req.Method, req.Route, time.Since(req.StartedAt))
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,370 @@
+package iredis
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gopkg.in/yaml.v3"
+)
+
+type Mode string
+
+const (
+	ModeSingle   Mode = "single"
+	ModeSentinel Mode = "sentinel"
+	ModeCluster  Mode = "cluster"
+)
+
+// Config is the redis client configuration, the file keys are shared by both
+// the JSON and YAML formats. Address is used by the single mode, while
+// Addresses lists the sentinels or the cluster seed nodes.
+type Config struct {
+	Mode                  Mode          `json:"mode" yaml:"mode"`
+	Address               string        `json:"address" yaml:"address"`
+	Addresses             []string      `json:"addresses" yaml:"addresses"`
+	MasterName            string        `json:"master_name" yaml:"master_name"`
+	Password              string        `json:"password" yaml:"password"`
+	SentinelPassword      string        `json:"sentinel_password" yaml:"sentinel_password"`
+	DB                    int           `json:"db" yaml:"db"`
+	PoolSize              int           `json:"pool_size" yaml:"pool_size"`
+	DialTimeout           time.Duration `json:"-" yaml:"-"`
+	ReadTimeout           time.Duration `json:"-" yaml:"-"`
+	WriteTimeout          time.Duration `json:"-" yaml:"-"`
+	TLS                   bool          `json:"tls" yaml:"tls"`
+	TLSServerName         string        `json:"tls_server_name" yaml:"tls_server_name"`
+	TLSInsecureSkipVerify bool          `json:"tls_insecure_skip_verify" yaml:"tls_insecure_skip_verify"`
+	ClientName            string        `json:"client_name" yaml:"client_name"`
+
+	// the startup policy, see Start.
+	StartupInitialBackoff time.Duration `json:"-" yaml:"-"`
+	StartupMaxBackoff     time.Duration `json:"-" yaml:"-"`
+	StartupMaxWait        time.Duration `json:"-" yaml:"-"`
+	StartupDegraded       bool          `json:"startup_degraded" yaml:"startup_degraded"`
+}
+
+// FieldError names the configuration field that is not valid.
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (ox *FieldError) Error() string {
+	return fmt.Sprintf("invalid redis config '%s': %s", ox.Field, ox.Reason)
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		Mode:                  ModeSingle,
+		Address:               "localhost:6379",
+		StartupInitialBackoff: 200 * time.Millisecond,
+		StartupMaxBackoff:     5 * time.Second,
+		StartupMaxWait:        30 * time.Second,
+	}
+}
+
+// LoadConfig starts from the default configuration, then applies the file
+// pointed by REDIS_CONFIG_FILE when it's set, then the REDIS_* env vars.
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+
+	if path := os.Getenv("REDIS_CONFIG_FILE"); path != "" {
+		fileConfig, err := LoadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		config = fileConfig
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// LoadConfigFile reads the configuration from a JSON or YAML file, the format
+// is picked from the file extension. The timeouts are written as durations,
+// e.g. "500ms".
+func LoadConfigFile(path string) (*Config, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the redis config file: %w", err)
+	}
+
+	var payload struct {
+		Config       `yaml:",inline"`
+		DialTimeout  string `json:"dial_timeout" yaml:"dial_timeout"`
+		ReadTimeout  string `json:"read_timeout" yaml:"read_timeout"`
+		WriteTimeout string `json:"write_timeout" yaml:"write_timeout"`
+
+		StartupInitialBackoff string `json:"startup_initial_backoff" yaml:"startup_initial_backoff"`
+		StartupMaxBackoff     string `json:"startup_max_backoff" yaml:"startup_max_backoff"`
+		StartupMaxWait        string `json:"startup_max_wait" yaml:"startup_max_wait"`
+	}
+	payload.Config = *DefaultConfig()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(raw, &payload)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &payload)
+	default:
+		return nil, fmt.Errorf("unsupported redis config file format '%s'", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the redis config file: %w", err)
+	}
+
+	config := payload.Config
+	durations := []struct {
+		field string
+		raw   string
+		value *time.Duration
+	}{
+		{"dial_timeout", payload.DialTimeout, &config.DialTimeout},
+		{"read_timeout", payload.ReadTimeout, &config.ReadTimeout},
+		{"write_timeout", payload.WriteTimeout, &config.WriteTimeout},
+		{"startup_initial_backoff", payload.StartupInitialBackoff, &config.StartupInitialBackoff},
+		{"startup_max_backoff", payload.StartupMaxBackoff, &config.StartupMaxBackoff},
+		{"startup_max_wait", payload.StartupMaxWait, &config.StartupMaxWait},
+	}
+	for _, d := range durations {
+		if d.raw == "" {
+			continue
+		}
+
+		value, err := time.ParseDuration(d.raw)
+		if err != nil {
+			return nil, &FieldError{Field: d.field, Reason: fmt.Sprintf("'%s' is not a duration", d.raw)}
+		}
+		*d.value = value
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (ox *Config) applyEnv() error {
+	if v, ok := os.LookupEnv("REDIS_MODE"); ok {
+		ox.Mode = Mode(v)
+	}
+	if v, ok := os.LookupEnv("REDIS_ADDRESS"); ok {
+		ox.Address = v
+	}
+	if v, ok := os.LookupEnv("REDIS_ADDRESSES"); ok {
+		ox.Addresses = nil
+		for _, address := range strings.Split(v, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				ox.Addresses = append(ox.Addresses, address)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv("REDIS_MASTER_NAME"); ok {
+		ox.MasterName = v
+	}
+	if v, ok := os.LookupEnv("REDIS_PASSWORD"); ok {
+		ox.Password = v
+	}
+	if v, ok := os.LookupEnv("REDIS_SENTINEL_PASSWORD"); ok {
+		ox.SentinelPassword = v
+	}
+	if v, ok := os.LookupEnv("REDIS_CLIENT_NAME"); ok {
+		ox.ClientName = v
+	}
+	if v, ok := os.LookupEnv("REDIS_TLS_SERVER_NAME"); ok {
+		ox.TLSServerName = v
+	}
+
+	ints := []struct {
+		env   string
+		value *int
+	}{
+		{"REDIS_DB", &ox.DB},
+		{"REDIS_POOL_SIZE", &ox.PoolSize},
+	}
+	for _, i := range ints {
+		raw, ok := os.LookupEnv(i.env)
+		if !ok {
+			continue
+		}
+
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return &FieldError{Field: i.env, Reason: fmt.Sprintf("'%s' is not an integer", raw)}
+		}
+		*i.value = value
+	}
+
+	durations := []struct {
+		env   string
+		value *time.Duration
+	}{
+		{"REDIS_DIAL_TIMEOUT", &ox.DialTimeout},
+		{"REDIS_READ_TIMEOUT", &ox.ReadTimeout},
+		{"REDIS_WRITE_TIMEOUT", &ox.WriteTimeout},
+		{"REDIS_STARTUP_INITIAL_BACKOFF", &ox.StartupInitialBackoff},
+		{"REDIS_STARTUP_MAX_BACKOFF", &ox.StartupMaxBackoff},
+		{"REDIS_STARTUP_MAX_WAIT", &ox.StartupMaxWait},
+	}
+	for _, d := range durations {
+		raw, ok := os.LookupEnv(d.env)
+		if !ok {
+			continue
+		}
+
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return &FieldError{Field: d.env, Reason: fmt.Sprintf("'%s' is not a duration", raw)}
+		}
+		*d.value = value
+	}
+
+	bools := []struct {
+		env   string
+		value *bool
+	}{
+		{"REDIS_TLS", &ox.TLS},
+		{"REDIS_TLS_INSECURE_SKIP_VERIFY", &ox.TLSInsecureSkipVerify},
+		{"REDIS_STARTUP_DEGRADED", &ox.StartupDegraded},
+	}
+	for _, b := range bools {
+		raw, ok := os.LookupEnv(b.env)
+		if !ok {
+			continue
+		}
+
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return &FieldError{Field: b.env, Reason: fmt.Sprintf("'%s' is not a boolean", raw)}
+		}
+		*b.value = value
+	}
+
+	return nil
+}
+
+func (ox *Config) Validate() error {
+	switch ox.Mode {
+	case ModeSingle:
+		if ox.Address == "" {
+			return &FieldError{Field: "address", Reason: "cannot be empty"}
+		}
+
+		if _, _, err := net.SplitHostPort(ox.Address); err != nil {
+			return &FieldError{Field: "address", Reason: err.Error()}
+		}
+
+	case ModeSentinel, ModeCluster:
+		if len(ox.Addresses) == 0 {
+			return &FieldError{Field: "addresses", Reason: fmt.Sprintf("cannot be empty in %s mode", ox.Mode)}
+		}
+
+		for _, address := range ox.Addresses {
+			if _, _, err := net.SplitHostPort(address); err != nil {
+				return &FieldError{Field: "addresses", Reason: err.Error()}
+			}
+		}
+
+	default:
+		return &FieldError{Field: "mode", Reason: fmt.Sprintf("unknown mode '%s'", ox.Mode)}
+	}
+
+	if ox.Mode == ModeSentinel && ox.MasterName == "" {
+		return &FieldError{Field: "master_name", Reason: "cannot be empty in sentinel mode"}
+	}
+
+	if ox.DB < 0 {
+		return &FieldError{Field: "db", Reason: "cannot be negative"}
+	}
+
+	if ox.Mode == ModeCluster && ox.DB != 0 {
+		return &FieldError{Field: "db", Reason: "must be 0 in cluster mode"}
+	}
+
+	if ox.PoolSize < 0 {
+		return &FieldError{Field: "pool_size", Reason: "cannot be negative"}
+	}
+
+	timeouts := []struct {
+		field string
+		value time.Duration
+	}{
+		{"dial_timeout", ox.DialTimeout},
+		{"read_timeout", ox.ReadTimeout},
+		{"write_timeout", ox.WriteTimeout},
+		{"startup_max_wait", ox.StartupMaxWait},
+	}
+	for _, t := range timeouts {
+		if t.value < 0 {
+			return &FieldError{Field: t.field, Reason: "cannot be negative"}
+		}
+	}
+
+	if ox.StartupInitialBackoff <= 0 {
+		return &FieldError{Field: "startup_initial_backoff", Reason: "must be positive"}
+	}
+
+	if ox.StartupMaxBackoff < ox.StartupInitialBackoff {
+		return &FieldError{Field: "startup_max_backoff", Reason: "cannot be less than startup_initial_backoff"}
+	}
+
+	if !ox.TLS && (ox.TLSServerName != "" || ox.TLSInsecureSkipVerify) {
+		return &FieldError{Field: "tls", Reason: "must be enabled to use the other tls options"}
+	}
+
+	return nil
+}
+
+// UniversalOptions translates the configuration into the go-redis options,
+// the addresses depend on the mode.
+func (ox *Config) UniversalOptions() *redis.UniversalOptions {
+	options := &redis.UniversalOptions{
+		Addrs:            ox.Addresses,
+		MasterName:       ox.MasterName,
+		Password:         ox.Password,
+		SentinelPassword: ox.SentinelPassword,
+		DB:               ox.DB,
+		PoolSize:         ox.PoolSize,
+		DialTimeout:      ox.DialTimeout,
+		ReadTimeout:      ox.ReadTimeout,
+		WriteTimeout:     ox.WriteTimeout,
+	}
+
+	if ox.Mode == ModeSingle {
+		options.Addrs = []string{ox.Address}
+	}
+
+	if ox.TLS {
+		options.TLSConfig = &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			ServerName:         ox.TLSServerName,
+			InsecureSkipVerify: ox.TLSInsecureSkipVerify,
+		}
+	}
+
+	if ox.ClientName != "" {
+		clientName := ox.ClientName
+		options.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+			return cn.ClientSetName(ctx, clientName).Err()
+		}
+	}
+
+	return options
+}
@@ -0,0 +1,215 @@
+package iredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+// FOR_EACH_MASTER_COMMAND names the call on a cluster node for the hooks,
+// e.g. in the metrics.
+const FOR_EACH_MASTER_COMMAND = "foreachmaster"
+
+var ErrDraining = errors.New("redis client is draining")
+
+type inflightKey struct{}
+
+// GracefulClient keeps track of the in-flight commands through a go-redis
+// hook, so closing it refuses the new commands and waits for the outstanding
+// ones instead of failing them with "redis: client is closed".
+type GracefulClient struct {
+	redis.UniversalClient
+
+	mu          sync.Mutex
+	inflight    int
+	draining    bool
+	drained     chan struct{}
+	drainedOnce sync.Once
+
+	// closing is closed once the client starts draining, it stops the work
+	// done in the background on behalf of the client.
+	closing     chan struct{}
+	closingOnce sync.Once
+
+	hooks []redis.Hook
+}
+
+func NewGracefulClient(client redis.UniversalClient) *GracefulClient {
+	gc := &GracefulClient{
+		UniversalClient: client,
+		drained:         make(chan struct{}),
+		closing:         make(chan struct{}),
+	}
+	gc.AddHook(inflightHook{client: gc})
+
+	return gc
+}
+
+// AddHook adds the hook to the client, it's kept so it can wrap the calls on
+// the cluster nodes as well, see ForEachMaster.
+func (ox *GracefulClient) AddHook(hook redis.Hook) {
+	ox.mu.Lock()
+	ox.hooks = append(ox.hooks, hook)
+	ox.mu.Unlock()
+
+	ox.UniversalClient.AddHook(hook)
+}
+
+// ForEachMaster calls the fn on every master. The commands sent to a cluster
+// node skip the hooks of the client, so each call goes through them as a
+// single FOR_EACH_MASTER_COMMAND instead, it's tracked and refused while
+// draining like any other command. A single node is its own master.
+func (ox *GracefulClient) ForEachMaster(ctx context.Context, fn func(ctx context.Context, client *redis.Client) error) error {
+	switch client := ox.UniversalClient.(type) {
+	case *redis.ClusterClient:
+		return client.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return ox.process(ctx, redis.NewCmd(ctx, FOR_EACH_MASTER_COMMAND), func(ctx context.Context) error {
+				return fn(ctx, node)
+			})
+		})
+
+	case *redis.Client:
+		return fn(ctx, client)
+
+	default:
+		return fmt.Errorf("iredis: %T has no masters to iterate", client)
+	}
+}
+
+// process runs the fn through the hooks of the client the way go-redis does,
+// the after hooks are only called for the before hooks that have run.
+func (ox *GracefulClient) process(ctx context.Context, cmd redis.Cmder, fn func(ctx context.Context) error) error {
+	ox.mu.Lock()
+	hooks := ox.hooks
+	ox.mu.Unlock()
+
+	var err error
+	i := 0
+	for ; i < len(hooks) && err == nil; i++ {
+		ctx, err = hooks[i].BeforeProcess(ctx, cmd)
+	}
+
+	if err == nil {
+		err = fn(ctx)
+	}
+	cmd.SetErr(err)
+
+	for i--; i >= 0; i-- {
+		if hookErr := hooks[i].AfterProcess(ctx, cmd); hookErr != nil {
+			err = hookErr
+			cmd.SetErr(err)
+		}
+	}
+
+	return err
+}
+
+func (ox *GracefulClient) InFlight() int {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return ox.inflight
+}
+
+// Close waits for the in-flight commands without a deadline, prefer
+// CloseContext.
+func (ox *GracefulClient) Close() error {
+	return ox.CloseContext(context.Background())
+}
+
+// CloseContext stops accepting new commands, waits for the in-flight ones
+// until the context is done, then closes the pool regardless.
+func (ox *GracefulClient) CloseContext(ctx context.Context) error {
+	ox.mu.Lock()
+	ox.draining = true
+	if ox.inflight == 0 {
+		ox.markDrained()
+	}
+	ox.mu.Unlock()
+
+	ox.closingOnce.Do(func() {
+		close(ox.closing)
+	})
+
+	var drainErr error
+	select {
+	case <-ox.drained:
+	case <-ctx.Done():
+		drainErr = fmt.Errorf("%d redis command(s) still in flight: %w", ox.InFlight(), ctx.Err())
+	}
+
+	stats := ox.PoolStats()
+	logger.Infof("iredis: closing the pool, hits: %d, misses: %d, timeouts: %d, total conns: %d, idle conns: %d, stale conns: %d.",
+		stats.Hits, stats.Misses, stats.Timeouts, stats.TotalConns, stats.IdleConns, stats.StaleConns)
+
+	if err := ox.UniversalClient.Close(); err != nil {
+		return err
+	}
+
+	return drainErr
+}
+
+// Closing is closed once the client starts closing.
+func (ox *GracefulClient) Closing() <-chan struct{} {
+	return ox.closing
+}
+
+func (ox *GracefulClient) acquire(ctx context.Context) (context.Context, error) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if ox.draining {
+		return ctx, ErrDraining
+	}
+
+	ox.inflight++
+	return context.WithValue(ctx, inflightKey{}, true), nil
+}
+
+func (ox *GracefulClient) release(ctx context.Context) {
+	// the after hooks are called even when the command has been refused.
+	if ctx.Value(inflightKey{}) == nil {
+		return
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.inflight--
+	if ox.draining && ox.inflight == 0 {
+		ox.markDrained()
+	}
+}
+
+func (ox *GracefulClient) markDrained() {
+	ox.drainedOnce.Do(func() {
+		close(ox.drained)
+	})
+}
+
+// inflightHook counts the commands of the client.
+type inflightHook struct {
+	client *GracefulClient
+}
+
+func (ox inflightHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ox.client.acquire(ctx)
+}
+
+func (ox inflightHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	ox.client.release(ctx)
+	return nil
+}
+
+func (ox inflightHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ox.client.acquire(ctx)
+}
+
+func (ox inflightHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	ox.client.release(ctx)
+	return nil
+}
@@ -0,0 +1,93 @@
+package iredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// countingHook counts the calls of its before and after hooks.
+type countingHook struct {
+	before int
+	after  int
+}
+
+func (ox *countingHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	ox.before++
+	return ctx, nil
+}
+
+func (ox *countingHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	ox.after++
+	return nil
+}
+
+func (ox *countingHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (ox *countingHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	return nil
+}
+
+func newTestClient(t *testing.T) (*GracefulClient, *countingHook) {
+	t.Helper()
+
+	client := NewGracefulClient(redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"}))
+	hook := &countingHook{}
+	client.AddHook(hook)
+
+	return client, hook
+}
+
+func TestGracefulClientProcess(t *testing.T) {
+	client, hook := newTestClient(t)
+	defer client.Close()
+
+	ctx := context.Background()
+	err := client.process(ctx, redis.NewCmd(ctx, FOR_EACH_MASTER_COMMAND), func(ctx context.Context) error {
+		if got := client.InFlight(); got != 1 {
+			t.Errorf("in flight = %d, want 1", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	if hook.before != 1 || hook.after != 1 {
+		t.Errorf("hook called %d/%d times, want once", hook.before, hook.after)
+	}
+
+	if got := client.InFlight(); got != 0 {
+		t.Errorf("in flight after the call = %d, want 0", got)
+	}
+}
+
+func TestGracefulClientProcessWhileDraining(t *testing.T) {
+	client, hook := newTestClient(t)
+	if err := client.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	ctx := context.Background()
+	called := false
+	err := client.process(ctx, redis.NewCmd(ctx, FOR_EACH_MASTER_COMMAND), func(ctx context.Context) error {
+		called = true
+		return nil
+	})
+	if !errors.Is(err, ErrDraining) {
+		t.Errorf("process = %v, want %v", err, ErrDraining)
+	}
+
+	if called {
+		t.Errorf("fn called while draining")
+	}
+
+	// the hook added after the in-flight one never ran, so it's not unwound.
+	if hook.before != 0 || hook.after != 0 {
+		t.Errorf("hook called %d/%d times, want never", hook.before, hook.after)
+	}
+}
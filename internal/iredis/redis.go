@@ -2,20 +2,145 @@ package iredis
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
 )
 
-func NewRedis() (*redis.Client, error) {
-	client := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
+// NewRedis creates the graceful client and waits for redis to respond
+// following the startup policy, see Start.
+func NewRedis(ctx context.Context, config *Config) (*GracefulClient, error) {
+	client := NewGracefulClient(NewClient(config))
+	if err := Start(ctx, client, config); err != nil {
+		_ = client.UniversalClient.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// Starter waits for redis the same way as NewRedis, as the start of the
+// client in a startup sequence. The pool is closed when it fails to start,
+// since a client that never started isn't disposed.
+func Starter(client *GracefulClient, config *Config) component.Startable {
+	return component.StartFunc(func(ctx context.Context) error {
+		if err := Start(ctx, client, config); err != nil {
+			_ = client.UniversalClient.Close()
+			return err
+		}
+
+		return nil
 	})
+}
+
+// NewClient returns the client of the topology chosen by the config mode
+// without connecting to it, the callers only depend on redis.UniversalClient
+// so they work against any of them.
+func NewClient(config *Config) redis.UniversalClient {
+	options := config.UniversalOptions()
 
-	result := client.Ping(context.Background())
-	if err := result.Err(); err != nil && err != redis.Nil {
-		return nil, result.Err()
+	switch config.Mode {
+	case ModeSentinel:
+		return redis.NewFailoverClient(options.Failover())
+	case ModeCluster:
+		return redis.NewClusterClient(options.Cluster())
+	default:
+		return redis.NewClient(options.Simple())
 	}
+}
 
-	return client, nil
+// Start pings redis with exponential backoff and jitter until it responds,
+// the max wait is exceeded or the context is done. In degraded mode it
+// returns right after the first failed attempt and keeps retrying in the
+// background until the client starts closing, while the readiness check
+// reports redis as unavailable.
+func Start(ctx context.Context, client *GracefulClient, config *Config) error {
+	err := ping(ctx, client)
+	if err == nil {
+		return nil
+	}
+
+	if config.StartupDegraded {
+		logger.Errf("iredis: redis is not reachable yet, starting in degraded mode: %+v", err)
+
+		retryCtx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-client.Closing():
+				cancel()
+			case <-retryCtx.Done():
+			}
+		}()
+
+		go func() {
+			defer cancel()
+
+			if err := waitForRedis(retryCtx, client, config, err); err != nil {
+				logger.Errf("iredis: gave up waiting for redis: %+v", err)
+			}
+		}()
+
+		return nil
+	}
+
+	return waitForRedis(ctx, client, config, err)
+}
+
+func waitForRedis(ctx context.Context, client redis.UniversalClient, config *Config, err error) error {
+	startedAt := time.Now()
+	backoff := config.StartupInitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		// there is no point to keep waiting for a client being closed.
+		if errors.Is(err, redis.ErrClosed) || errors.Is(err, ErrDraining) {
+			return err
+		}
+
+		wait := withJitter(backoff)
+		if config.StartupMaxWait > 0 && time.Since(startedAt)+wait > config.StartupMaxWait {
+			return fmt.Errorf("redis is not reachable after %d attempt(s): %w", attempt, err)
+		}
+
+		logger.Infof("iredis: attempt #%d to reach redis failed: %v, retrying in %s.", attempt, err, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("stopped waiting for redis: %w", ctx.Err())
+		}
+
+		if err = ping(ctx, client); err == nil {
+			logger.Infof("iredis: redis is reachable after %d attempt(s).", attempt+1)
+			return nil
+		}
+
+		backoff *= 2
+		if backoff > config.StartupMaxBackoff {
+			backoff = config.StartupMaxBackoff
+		}
+	}
+}
+
+func ping(ctx context.Context, client redis.UniversalClient) error {
+	err := client.Ping(ctx).Err()
+	if err == redis.Nil {
+		return nil
+	}
+
+	return err
+}
+
+// withJitter spreads the backoff between its half and its full value, so the
+// restarted instances don't hit redis at the same time.
+func withJitter(backoff time.Duration) time.Duration {
+	half := int64(backoff / 2)
+	return time.Duration(half + rand.Int63n(half+1))
 }
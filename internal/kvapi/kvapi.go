@@ -0,0 +1,169 @@
+package kvapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/iredis"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+)
+
+// MAX_KEY_LENGTH keeps the keys readable in the URL.
+const MAX_KEY_LENGTH = 512
+
+var (
+	ErrInvalidKey = errors.New("invalid key")
+	ErrInvalidTTL = errors.New("invalid ttl")
+)
+
+// Entry is the response of reading or writing a key, TTL is only set on
+// writes.
+type Entry struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+// PutRequest is the payload of writing a key. TTL is a duration (e.g. "30s"),
+// "0s" never expires, while leaving it out uses the default TTL.
+type PutRequest struct {
+	Value string  `json:"value"`
+	TTL   *string `json:"ttl"`
+}
+
+type DeleteResult struct {
+	Key     string `json:"key"`
+	Deleted bool   `json:"deleted"`
+}
+
+type KeyList struct {
+	Prefix string   `json:"prefix"`
+	Keys   []string `json:"keys"`
+}
+
+// API holds the key-value semantics shared by the net/http mux and the
+// bivrost router, so both only translate their requests and responses.
+type API struct {
+	store  kvstore.KeyValueStore
+	config *kvstore.Config
+}
+
+func New(store kvstore.KeyValueStore, config *kvstore.Config) *API {
+	return &API{
+		store:  store,
+		config: config,
+	}
+}
+
+func (ox *API) Get(ctx context.Context, key string) (*Entry, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	value, err := ox.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Key:   key,
+		Value: value,
+	}, nil
+}
+
+func (ox *API) Put(ctx context.Context, key string, payload PutRequest) (*Entry, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	ttl := ox.config.TTL
+	if payload.TTL != nil {
+		parsed, err := time.ParseDuration(*payload.TTL)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("%w '%s'", ErrInvalidTTL, *payload.TTL)
+		}
+
+		ttl = parsed
+	}
+
+	if err := ox.store.Set(ctx, key, payload.Value, ttl); err != nil {
+		return nil, err
+	}
+
+	return &Entry{
+		Key:   key,
+		Value: payload.Value,
+		TTL:   ttl.String(),
+	}, nil
+}
+
+func (ox *API) Delete(ctx context.Context, key string) (*DeleteResult, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	if err := ox.store.Delete(ctx, key); err != nil {
+		return nil, err
+	}
+
+	return &DeleteResult{
+		Key:     key,
+		Deleted: true,
+	}, nil
+}
+
+func (ox *API) List(ctx context.Context, prefix string) (*KeyList, error) {
+	keys, err := ox.store.Keys(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyList{
+		Prefix: prefix,
+		Keys:   keys,
+	}, nil
+}
+
+// StatusCode maps the error of the API to its http status. The errors
+// carrying their own status keep it, while the ones caused by a cancelled
+// request, a server or a redis client shutting down are unavailable.
+func StatusCode(err error) int {
+	var coded interface{ StatusCode() int }
+
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.As(err, &coded):
+		return coded.StatusCode()
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded), errors.Is(err, iredis.ErrDraining):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrInvalidKey), errors.Is(err, ErrInvalidTTL):
+		return http.StatusBadRequest
+	case errors.Is(err, kvstore.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("%w: the key is empty", ErrInvalidKey)
+	}
+
+	if len(key) > MAX_KEY_LENGTH {
+		return fmt.Errorf("%w: the key is longer than %d bytes", ErrInvalidKey, MAX_KEY_LENGTH)
+	}
+
+	// a key is a single path segment, as bivrost's "/kv/:key" only matches
+	// those.
+	if strings.Contains(key, "/") {
+		return fmt.Errorf("%w: the key contains '/'", ErrInvalidKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package kvapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/iredis"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+)
+
+func newAPI() *API {
+	return New(kvstore.NewMemoryStore(), &kvstore.Config{
+		TTL: time.Minute,
+	})
+}
+
+func TestPut(t *testing.T) {
+	ctx := context.Background()
+	ttl := func(raw string) *string {
+		return &raw
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		payload PutRequest
+		wantTTL string
+		wantErr error
+	}{
+		{name: "default ttl", key: "user:1", payload: PutRequest{Value: "alice"}, wantTTL: "1m0s"},
+		{name: "own ttl", key: "user:1", payload: PutRequest{Value: "alice", TTL: ttl("30s")}, wantTTL: "30s"},
+		{name: "no expiry", key: "user:1", payload: PutRequest{Value: "alice", TTL: ttl("0s")}, wantTTL: "0s"},
+		{name: "invalid ttl", key: "user:1", payload: PutRequest{TTL: ttl("soon")}, wantErr: ErrInvalidTTL},
+		{name: "negative ttl", key: "user:1", payload: PutRequest{TTL: ttl("-1s")}, wantErr: ErrInvalidTTL},
+		{name: "empty key", key: "", payload: PutRequest{Value: "alice"}, wantErr: ErrInvalidKey},
+		{name: "nested key", key: "user/1", payload: PutRequest{Value: "alice"}, wantErr: ErrInvalidKey},
+		{name: "long key", key: strings.Repeat("k", MAX_KEY_LENGTH+1), payload: PutRequest{Value: "alice"}, wantErr: ErrInvalidKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := newAPI().Put(ctx, tt.key, tt.payload)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entry.TTL != tt.wantTTL {
+				t.Errorf("ttl = %s, want %s", entry.TTL, tt.wantTTL)
+			}
+		})
+	}
+}
+
+func TestGetDelete(t *testing.T) {
+	ctx := context.Background()
+	api := newAPI()
+
+	if _, err := api.Put(ctx, "user:1", PutRequest{Value: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := api.Get(ctx, "user:1")
+	if err != nil || entry.Value != "alice" {
+		t.Fatalf("got %+v, %v, want alice", entry, err)
+	}
+
+	if _, err := api.Delete(ctx, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := api.Get(ctx, "user:1"); !errors.Is(err, kvstore.ErrNotFound) {
+		t.Errorf("got %v, want %v", err, kvstore.ErrNotFound)
+	}
+
+	if _, err := api.Delete(ctx, "user:1"); !errors.Is(err, kvstore.ErrNotFound) {
+		t.Errorf("got %v, want %v", err, kvstore.ErrNotFound)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "ok", err: nil, want: 200},
+		{name: "invalid key", err: fmt.Errorf("%w: the key is empty", ErrInvalidKey), want: 400},
+		{name: "invalid ttl", err: ErrInvalidTTL, want: 400},
+		{name: "not found", err: kvstore.ErrNotFound, want: 404},
+		{name: "not accepting", err: &lifecycle.NotAcceptingError{State: lifecycle.StateStopping}, want: 503},
+		{name: "cancelled", err: context.Canceled, want: 503},
+		{name: "deadline exceeded", err: fmt.Errorf("get: %w", context.DeadlineExceeded), want: 503},
+		{name: "redis draining", err: iredis.ErrDraining, want: 503},
+		{name: "unknown", err: errors.New("connection reset"), want: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatusCode(tt.err); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,320 @@
+// Package kvservice declares the key-value service of the examples once, every
+// cmd only picks the runner and the servers it's run with, so the graceful
+// shutdown of os/signal, FX and bivrost can be compared.
+package kvservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/koinworks/asgard-bivrost/libs"
+	"github.com/koinworks/asgard-heimdal/constants/cservice"
+	"github.com/koinworks/asgard-heimdal/models"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/admin"
+	"github.com/luthfikw/example.graceful-shutdown/internal/app"
+	"github.com/luthfikw/example.graceful-shutdown/internal/bvrouter"
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+	"github.com/luthfikw/example.graceful-shutdown/internal/fault"
+	"github.com/luthfikw/example.graceful-shutdown/internal/health"
+	"github.com/luthfikw/example.graceful-shutdown/internal/httprouter"
+	"github.com/luthfikw/example.graceful-shutdown/internal/i18n"
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/iredis"
+	"github.com/luthfikw/example.graceful-shutdown/internal/kvstore"
+	"github.com/luthfikw/example.graceful-shutdown/internal/lifecycle"
+	"github.com/luthfikw/example.graceful-shutdown/internal/metrics"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+const (
+	API_DURATION   = 7 * time.Second
+	PRE_STOP_DELAY = 5 * time.Second
+)
+
+// Server is an http server serving the api, the label tells it apart in the
+// logs of the requests.
+type Server struct {
+	Label   string
+	Address string
+}
+
+// Options tell the examples apart.
+type Options struct {
+	// Servers are the http servers, the service may only be served by
+	// bivrost's gateway.
+	Servers []Server
+
+	// Workers declares the components disposed in the workers phase, the
+	// budget leaves the phase out without them.
+	Workers bool
+}
+
+func (ox Options) phases() []shutdown.PhaseWeight {
+	if ox.Workers {
+		return shutdown.DefaultPhases()
+	}
+
+	// there are no workers to dispose, their share is left to the resources.
+	return shutdown.DefaultPhasesOf(shutdown.PhasePreStop, shutdown.PhaseHTTPDrain, shutdown.PhaseResources)
+}
+
+// Main runs the service with the named runner until it's shut down, then
+// exits with the code of the shutdown.
+func Main(runnerName string, options Options) {
+	redisConfig, err := iredis.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	budget, err := shutdown.NewBudgetFromEnv(options.phases()...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	svc := app.NewService(budget)
+	svc.Tracker = inflight.NewTracker()
+
+	// the client only connects once the runner starts it, see declare.
+	redisClient := iredis.NewGracefulClient(iredis.NewClient(redisConfig))
+
+	svc.HealthChecker = newHealthChecker(redisClient, svc.Lifecycle)
+
+	appMetrics := metrics.New(svc.Tracker)
+	redisClient.AddHook(appMetrics.RedisHook())
+
+	api, err := newKeyValueAPI(redisClient)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	newHandler := func(label string) http.Handler {
+		return httprouter.NewHTTPServerMux(label, api.injector, api.store, api.storeConfig, svc.HealthChecker, svc.Tracker, api.catalog, appMetrics, svc.Lifecycle)
+	}
+	if err := declare(svc, options, redisConfig, redisClient, newHandler); err != nil {
+		log.Fatal(err)
+	}
+
+	runner, err := newRunner(runnerName, redisConfig, svc, api, appMetrics)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	metricsServer := metrics.NewServer(metrics.LoadConfig(), appMetrics)
+	if err := metricsServer.Start(); err != nil {
+		log.Fatal(err)
+	}
+
+	adminServer, err := newAdminServer(svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("running the service with the '%s' runner.\n", runnerName)
+	report, err := runner.Run(svc)
+	if report == nil {
+		log.Fatal(err)
+	}
+
+	report.Log()
+	if err != nil {
+		log.Println(err)
+	}
+
+	// the metrics and admin servers are stopped last, so the shutdown itself
+	// is observed. They share the margin kept out of the budget.
+	finalCtx, cancelFinal := svc.Budget.Grace(context.Background())
+	defer cancelFinal()
+
+	if err := metricsServer.Stop(finalCtx, report); err != nil {
+		log.Println(err)
+	}
+	if err := adminServer.Stop(finalCtx); err != nil {
+		log.Println(err)
+	}
+
+	shutdown.Exit(report.ExitCode())
+}
+
+// keyValueAPI is served by the http server, and by the bivrost gateway when
+// bivrost runs the service.
+type keyValueAPI struct {
+	injector    *fault.Injector
+	store       kvstore.KeyValueStore
+	storeConfig *kvstore.Config
+	catalog     *i18n.Catalog
+}
+
+func newKeyValueAPI(redisClient redis.UniversalClient) (*keyValueAPI, error) {
+	storeConfig, err := kvstore.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := i18n.LoadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	catalog.LogMissing()
+
+	injector, err := fault.LoadInjector(fault.SlowRule(API_DURATION))
+	if err != nil {
+		return nil, err
+	}
+
+	return &keyValueAPI{
+		injector:    injector,
+		store:       kvstore.NewRedisStore(redisClient),
+		storeConfig: storeConfig,
+		catalog:     catalog,
+	}, nil
+}
+
+func newRunner(name string, redisConfig *iredis.Config, svc *app.Service, api *keyValueAPI, appMetrics *metrics.Metrics) (app.Runner, error) {
+	switch name {
+	case app.RUNNER_SIGNAL:
+		return app.NewSignalRunner(), nil
+
+	case app.RUNNER_FX:
+		return app.NewFxRunner(), nil
+
+	case app.RUNNER_BIVROST:
+		registry, err := libs.InitRegistry(libs.RegistryConfig{
+			Service: &models.Service{
+				Class:   cservice.ServiceClassUtility,
+				Key:     "asgard-tst",
+				Name:    "Asgard Tst",
+				Version: "v1.0.0",
+				Host:    "localhost",
+				Port:    4100,
+			},
+			Address:  redisConfig.Address,
+			Password: redisConfig.Password,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		server, err := libs.NewServer(registry)
+		if err != nil {
+			return nil, err
+		}
+
+		// the gateway requests are tracked along with those of the http
+		// servers, so they're drained the same way.
+		bvrouter.SetupBivrostRouter("0", api.injector, server.AsGatewayService("/test"), api.store, api.storeConfig, svc.Tracker, api.catalog, svc.HardDeadline, appMetrics, svc.Lifecycle)
+
+		return app.NewBivrostRunner(server), nil
+	}
+
+	return nil, fmt.Errorf("%w: '%s'", app.ErrUnknownRunner, name)
+}
+
+// declare is the only place the servers and components are declared, every
+// runner starts and shuts them down the same way.
+func declare(svc *app.Service, options Options, redisConfig *iredis.Config, redisClient *iredis.GracefulClient, newHandler func(label string) http.Handler) error {
+	for _, s := range options.Servers {
+		svc.AddServer(fmt.Sprintf("http.server(%s)", s.Label), &http.Server{
+			Addr:    s.Address,
+			Handler: newHandler(s.Label),
+		})
+	}
+
+	err := svc.AddComponent(app.Component{
+		Name:     "redis.client",
+		Phase:    shutdown.PhaseResources,
+		Starter:  iredis.Starter(redisClient, redisConfig),
+		Disposer: component.DisposeContextFunc(redisClient.CloseContext),
+		Critical: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if !options.Workers {
+		return nil
+	}
+
+	workers := []app.Component{
+		{
+			Name:  "component-1",
+			Phase: shutdown.PhaseWorkers,
+			Disposer: &component.Component{
+				Label: "component-1",
+			},
+		},
+		{
+			Name:  "component-2",
+			Phase: shutdown.PhaseWorkers,
+			Disposer: &component.Component{
+				Label:           "component-2",
+				DisposeDuration: time.Second,
+			},
+		},
+		{
+			Name:  "component-3",
+			Phase: shutdown.PhaseWorkers,
+			Disposer: &component.Component{
+				Label:           "component-3",
+				DisposeDuration: time.Second * 5,
+			},
+		},
+		{
+			Name:  "component-4",
+			Phase: shutdown.PhaseWorkers,
+			Disposer: &component.Component{
+				Label:           "component-4",
+				DisposeDuration: time.Second * 3,
+				DisposeError:    errors.New("failed to dispose component-4"),
+			},
+		},
+	}
+
+	for _, c := range workers {
+		if err := svc.AddComponent(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newHealthChecker(redisClient redis.UniversalClient, appLifecycle *lifecycle.Lifecycle) *health.Checker {
+	checker := health.NewChecker(PRE_STOP_DELAY)
+	checker.AddReadinessCheck("lifecycle", appLifecycle.ReadinessCheck)
+	checker.AddReadinessCheck("redis", func(ctx context.Context) error {
+		return redisClient.Ping(ctx).Err()
+	})
+	return checker
+}
+
+// newAdminServer starts the admin server once the service is ready, since
+// every runner handles the termination signals by then.
+func newAdminServer(svc *app.Service) (*admin.Server, error) {
+	config, err := admin.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	adminState := admin.New(svc.Budget, svc.Recorder)
+	adminState.Trigger = svc.RequestShutdown
+	adminState.Follow(svc.Lifecycle)
+
+	server := admin.NewServer(config, adminState)
+	svc.Lifecycle.OnTransition(func(from lifecycle.State, to lifecycle.State) {
+		if to != lifecycle.StateReady {
+			return
+		}
+
+		if err := server.Start(); err != nil {
+			log.Println(err)
+		}
+	})
+
+	return server, nil
+}
@@ -0,0 +1,56 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var ErrNotFound = errors.New("key not found")
+
+// KeyValueStore is the storage used by the handlers, so they can run against
+// redis or the in-memory store without knowing the difference.
+type KeyValueStore interface {
+	// Get returns ErrNotFound when the key doesn't exist or has expired.
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set keeps the value for the ttl, a zero ttl never expires.
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+
+	// Delete returns ErrNotFound when there's nothing to delete.
+	Delete(ctx context.Context, key string) error
+
+	// Keys lists the keys starting with the prefix in lexical order, an empty
+	// prefix lists every key.
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Config holds the TTL of the values that are set without one.
+type Config struct {
+	TTL time.Duration
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		TTL: time.Hour,
+	}
+}
+
+// LoadConfig starts from the default configuration, then applies the KV_TTL
+// (e.g. "30m") env var.
+func LoadConfig() (*Config, error) {
+	config := DefaultConfig()
+
+	if raw := os.Getenv("KV_TTL"); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid KV_TTL '%s'", raw)
+		}
+
+		config.TTL = ttl
+	}
+
+	return config, nil
+}
@@ -0,0 +1,117 @@
+package kvstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (ox memoryEntry) isExpired(now time.Time) bool {
+	return !ox.expiresAt.IsZero() && !now.Before(ox.expiresAt)
+}
+
+// MemoryStore keeps the values in the process, the expired ones are dropped
+// lazily once they are read.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]memoryEntry),
+	}
+}
+
+func (ox *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	entry, ok := ox.entries[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	if entry.isExpired(time.Now()) {
+		delete(ox.entries, key)
+		return "", ErrNotFound
+	}
+
+	return entry.value, nil
+}
+
+func (ox *MemoryStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entry := memoryEntry{
+		value: value,
+	}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.entries[key] = entry
+	return nil
+}
+
+func (ox *MemoryStore) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	entry, ok := ox.entries[key]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(ox.entries, key)
+	if entry.isExpired(time.Now()) {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (ox *MemoryStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	now := time.Now()
+	keys := make([]string, 0)
+	for key, entry := range ox.entries {
+		if entry.isExpired(now) {
+			delete(ox.entries, key)
+			continue
+		}
+
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,122 @@
+package kvstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreGetSet(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if _, err := store.Get(ctx, "user:1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Set(ctx, "user:1", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := store.Get(ctx, "user:1")
+	if err != nil || value != "alice" {
+		t.Fatalf("got %q, %v, want %q", value, err, "alice")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.Set(ctx, "session", "token", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "session"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get of an expired key: got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Set(ctx, "session", "token", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if err := store.Delete(ctx, "session"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("delete of an expired key: got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	if err := store.Delete(ctx, "user:1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := store.Set(ctx, "user:1", "alice", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Delete(ctx, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get(ctx, "user:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	for key, ttl := range map[string]time.Duration{
+		"user:2":  0,
+		"user:1":  0,
+		"user:3":  time.Millisecond,
+		"order:1": 0,
+	} {
+		if err := store.Set(ctx, key, "value", ttl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"order:1", "user:1", "user:2"}},
+		{prefix: "user:", want: []string{"user:1", "user:2"}},
+		{prefix: "cart:", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		keys, err := store.Keys(ctx, tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(keys, tt.want) {
+			t.Errorf("keys of %q = %v, want %v", tt.prefix, keys, tt.want)
+		}
+	}
+}
+
+func TestMemoryStoreCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store := NewMemoryStore()
+	if err := store.Set(ctx, "user:1", "alice", 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := store.Keys(ctx, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,124 @@
+package kvstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// SCAN_COUNT is the hint of how many keys a single SCAN call walks through.
+const SCAN_COUNT = 100
+
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (ox *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	value, err := ox.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+
+	return value, err
+}
+
+func (ox *RedisStore) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return ox.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (ox *RedisStore) Delete(ctx context.Context, key string) error {
+	deleted, err := ox.client.Del(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Keys walks the keyspace with SCAN instead of KEYS, so a large keyspace
+// doesn't block the server. Every master is scanned on a cluster, since the
+// keys are spread across them.
+func (ox *RedisStore) Keys(ctx context.Context, prefix string) ([]string, error) {
+	match := escapePattern(prefix) + "*"
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[string]struct{})
+	)
+	collect := func(ctx context.Context, client redis.Cmdable) error {
+		var cursor uint64
+		for {
+			batch, next, err := client.Scan(ctx, cursor, match, SCAN_COUNT).Result()
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			for _, key := range batch {
+				// SCAN may return the same key more than once.
+				seen[key] = struct{}{}
+			}
+			mu.Unlock()
+
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+
+	var err error
+	if masters, ok := ox.client.(masterIterator); ok {
+		err = masters.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return collect(ctx, client)
+		})
+	} else {
+		err = collect(ctx, ox.client)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// masterIterator is implemented by redis.ClusterClient, and by the wrappers
+// such as iredis.GracefulClient, which runs each node call through its hooks.
+type masterIterator interface {
+	ForEachMaster(ctx context.Context, fn func(ctx context.Context, client *redis.Client) error) error
+}
+
+// escapePattern escapes the glob characters of the prefix, so it's matched
+// literally by SCAN.
+func escapePattern(prefix string) string {
+	var builder strings.Builder
+	for _, r := range prefix {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(r)
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,197 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+var ErrInvalidTransition = errors.New("invalid lifecycle transition")
+
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateReady    State = "ready"
+	StateDraining State = "draining"
+	StateStopping State = "stopping"
+	StateStopped  State = "stopped"
+	StateFailed   State = "failed"
+)
+
+// transitions lists the states reachable from each state. Every state but the
+// terminal ones can fail, and the draining can be skipped by an immediate
+// shutdown.
+var transitions = map[State][]State{
+	StateStarting: {StateReady, StateStopping, StateFailed},
+	StateReady:    {StateDraining, StateStopping, StateFailed},
+	StateDraining: {StateStopping, StateFailed},
+	StateStopping: {StateStopped, StateFailed},
+}
+
+// ranks orders the states, Failed shares the rank of Stopped since both are
+// terminal.
+var ranks = map[State]int{
+	StateStarting: 0,
+	StateReady:    1,
+	StateDraining: 2,
+	StateStopping: 3,
+	StateStopped:  4,
+	StateFailed:   4,
+}
+
+func (ox State) IsTerminal() bool {
+	return ox == StateStopped || ox == StateFailed
+}
+
+func (ox State) canTransition(to State) bool {
+	for _, next := range transitions[ox] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NotAcceptingError is returned for the new work once the service is stopping
+// or before it's ready, it's served as 503 so the clients retry elsewhere.
+type NotAcceptingError struct {
+	State State
+}
+
+func (ox *NotAcceptingError) Error() string {
+	return fmt.Sprintf("the service is %s, not accepting new requests", ox.State)
+}
+
+func (ox *NotAcceptingError) StatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+type TransitionFunc func(from State, to State)
+
+// Lifecycle is the state of the process, from Starting through Ready, Draining
+// and Stopping to Stopped, or Failed at any point before that.
+type Lifecycle struct {
+	// transitionMu serializes the transitions along with their notification,
+	// so the observers see them in order.
+	transitionMu sync.Mutex
+
+	mu        sync.RWMutex
+	state     State
+	observers []TransitionFunc
+}
+
+func New() *Lifecycle {
+	return &Lifecycle{
+		state: StateStarting,
+	}
+}
+
+func (ox *Lifecycle) State() State {
+	ox.mu.RLock()
+	defer ox.mu.RUnlock()
+
+	return ox.state
+}
+
+// OnTransition subscribes the fn to every later transition. The fn is called
+// synchronously in the order of subscription, it must not transition the
+// lifecycle itself.
+func (ox *Lifecycle) OnTransition(fn TransitionFunc) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.observers = append(ox.observers, fn)
+}
+
+// Transition moves the lifecycle into the given state, the transition into
+// the current state is a no-op, so the parallel hooks can report the same
+// state.
+func (ox *Lifecycle) Transition(to State) error {
+	return ox.transition(to, false)
+}
+
+func (ox *Lifecycle) transition(to State, skipPassed bool) error {
+	ox.transitionMu.Lock()
+	defer ox.transitionMu.Unlock()
+
+	ox.mu.Lock()
+	from := ox.state
+	if from == to || (skipPassed && ranks[to] <= ranks[from]) {
+		ox.mu.Unlock()
+		return nil
+	}
+
+	if !from.canTransition(to) {
+		ox.mu.Unlock()
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+	}
+
+	ox.state = to
+	observers := ox.observers
+	ox.mu.Unlock()
+
+	logger.Infof("lifecycle: %s -> %s.", from, to)
+	for _, fn := range observers {
+		fn(from, to)
+	}
+
+	return nil
+}
+
+// Advance is Transition for the callers with nowhere to return the error, a
+// state already passed is ignored, e.g. a hook reporting Draining after
+// another one reported Stopping, while any other invalid transition is only
+// logged.
+func (ox *Lifecycle) Advance(to State) {
+	if err := ox.transition(to, true); err != nil {
+		logger.Errf("lifecycle: %+v", err)
+	}
+}
+
+// Finish moves the lifecycle through Stopping into Stopped, or into Failed
+// when the shutdown ended with any error.
+func (ox *Lifecycle) Finish(errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			ox.Advance(StateFailed)
+			return
+		}
+	}
+
+	ox.Advance(StateStopping)
+	ox.Advance(StateStopped)
+}
+
+// Accept returns a NotAcceptingError unless the lifecycle is ready or
+// draining, the routers call it before taking new work.
+//
+// Draining only fails the readiness on purpose. The load balancer keeps
+// routing requests to the instance until it notices, that's what the pre-stop
+// delay waits for, and rejecting them would fail requests another instance
+// could have served. The new work is only rejected from Stopping on, once the
+// servers no longer take new connections anyway.
+func (ox *Lifecycle) Accept() error {
+	switch state := ox.State(); state {
+	case StateReady, StateDraining:
+		return nil
+	default:
+		return &NotAcceptingError{State: state}
+	}
+}
+
+// ReadinessCheck reports the instance as unavailable unless it's ready, so the
+// load balancer drops it as soon as it's draining, see
+// health.Checker.AddReadinessCheck.
+func (ox *Lifecycle) ReadinessCheck(ctx context.Context) error {
+	if state := ox.State(); state != StateReady {
+		return &NotAcceptingError{State: state}
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// walk transitions a new lifecycle through the states.
+func walk(t *testing.T, states ...State) *Lifecycle {
+	t.Helper()
+
+	lc := New()
+	for _, state := range states {
+		if err := lc.Transition(state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return lc
+}
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    []State
+		to      State
+		wantErr bool
+	}{
+		{name: "starting to ready", to: StateReady},
+		{name: "starting to stopping", to: StateStopping},
+		{name: "starting to failed", to: StateFailed},
+		{name: "ready to draining", path: []State{StateReady}, to: StateDraining},
+		{name: "ready to stopping", path: []State{StateReady}, to: StateStopping},
+		{name: "draining to stopping", path: []State{StateReady, StateDraining}, to: StateStopping},
+		{name: "draining to failed", path: []State{StateReady, StateDraining}, to: StateFailed},
+		{name: "stopping to stopped", path: []State{StateStopping}, to: StateStopped},
+		{name: "stopping to failed", path: []State{StateStopping}, to: StateFailed},
+		{name: "same state", path: []State{StateReady}, to: StateReady},
+		{name: "starting to draining", to: StateDraining, wantErr: true},
+		{name: "starting to stopped", to: StateStopped, wantErr: true},
+		{name: "draining to ready", path: []State{StateReady, StateDraining}, to: StateReady, wantErr: true},
+		{name: "stopping to draining", path: []State{StateStopping}, to: StateDraining, wantErr: true},
+		{name: "stopped to ready", path: []State{StateStopping, StateStopped}, to: StateReady, wantErr: true},
+		{name: "failed to stopped", path: []State{StateFailed}, to: StateStopped, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lc := walk(t, tt.path...)
+			from := lc.State()
+
+			err := lc.Transition(tt.to)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("got %v, want %v", err, ErrInvalidTransition)
+				}
+
+				if state := lc.State(); state != from {
+					t.Errorf("state = %s after the invalid transition, want %s", state, from)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if state := lc.State(); state != tt.to {
+				t.Errorf("state = %s, want %s", state, tt.to)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	lc := walk(t, StateReady, StateStopping)
+
+	// a state already passed is ignored rather than going back.
+	lc.Advance(StateDraining)
+	if state := lc.State(); state != StateStopping {
+		t.Errorf("state = %s, want %s", state, StateStopping)
+	}
+
+	lc.Finish()
+	if state := lc.State(); state != StateStopped {
+		t.Errorf("state = %s, want %s", state, StateStopped)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	lc := walk(t, StateReady, StateDraining)
+	lc.Finish(nil, errors.New("failed to dispose"))
+
+	if state := lc.State(); state != StateFailed {
+		t.Errorf("state = %s, want %s", state, StateFailed)
+	}
+}
+
+func TestOnTransitionOrder(t *testing.T) {
+	var (
+		mu  sync.Mutex
+		got []string
+	)
+	observe := func(name string) TransitionFunc {
+		return func(from State, to State) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			got = append(got, name+":"+string(from)+"->"+string(to))
+		}
+	}
+
+	lc := New()
+	lc.OnTransition(observe("first"))
+	lc.OnTransition(observe("second"))
+
+	lc.Advance(StateReady)
+	lc.Advance(StateDraining)
+	lc.Advance(StateDraining)
+	lc.Finish()
+
+	want := []string{
+		"first:starting->ready",
+		"second:starting->ready",
+		"first:ready->draining",
+		"second:ready->draining",
+		"first:draining->stopping",
+		"second:draining->stopping",
+		"first:stopping->stopped",
+		"second:stopping->stopped",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transitions = %v, want %v", got, want)
+	}
+}
+
+func TestAccept(t *testing.T) {
+	tests := []struct {
+		path       []State
+		wantAccept bool
+		wantReady  bool
+	}{
+		{path: nil, wantAccept: false, wantReady: false},
+		{path: []State{StateReady}, wantAccept: true, wantReady: true},
+		{path: []State{StateReady, StateDraining}, wantAccept: true, wantReady: false},
+		{path: []State{StateReady, StateStopping}, wantAccept: false, wantReady: false},
+		{path: []State{StateStopping, StateStopped}, wantAccept: false, wantReady: false},
+		{path: []State{StateFailed}, wantAccept: false, wantReady: false},
+	}
+
+	for _, tt := range tests {
+		lc := walk(t, tt.path...)
+		state := lc.State()
+
+		t.Run(string(state), func(t *testing.T) {
+			err := lc.Accept()
+			if accepted := err == nil; accepted != tt.wantAccept {
+				t.Errorf("accepted = %v, want %v", accepted, tt.wantAccept)
+			}
+
+			var notAccepting *NotAcceptingError
+			if err != nil && (!errors.As(err, &notAccepting) || notAccepting.State != state || notAccepting.StatusCode() != 503) {
+				t.Errorf("got %v, want a NotAcceptingError of %s", err, state)
+			}
+
+			if ready := lc.ReadinessCheck(context.Background()) == nil; ready != tt.wantReady {
+				t.Errorf("ready = %v, want %v", ready, tt.wantReady)
+			}
+		})
+	}
+}
+
+// TestAcceptWhileDraining pins the pre-stop behavior, the draining instance
+// fails its readiness so the load balancer drops it, but keeps serving the
+// requests routed to it meanwhile.
+func TestAcceptWhileDraining(t *testing.T) {
+	lc := walk(t, StateReady, StateDraining)
+
+	if err := lc.ReadinessCheck(context.Background()); err == nil {
+		t.Error("ready while draining")
+	}
+
+	if err := lc.Accept(); err != nil {
+		t.Errorf("rejected while draining: %v", err)
+	}
+
+	lc.Advance(StateStopping)
+	if err := lc.Accept(); err == nil {
+		t.Error("accepted while stopping")
+	}
+}
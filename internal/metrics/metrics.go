@@ -0,0 +1,153 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/inflight"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+const NAMESPACE = "graceful_shutdown"
+
+// Metrics owns its own registry, so every binary exposes the same set of
+// metrics regardless of what its dependencies register globally.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	redisDuration   *prometheus.HistogramVec
+	redisErrors     *prometheus.CounterVec
+	disposeDuration *prometheus.GaugeVec
+	disposeOutcomes *prometheus.CounterVec
+	shutdownTime    prometheus.Gauge
+}
+
+// New registers the metrics, the in-flight gauge reads the tracker shared by
+// the routers.
+func New(tracker *inflight.Tracker) *Metrics {
+	ox := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_requests_total",
+			Help:      "The number of the handled http requests.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_request_duration_seconds",
+			Help:      "The latency of the handled http requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		redisDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: NAMESPACE,
+			Name:      "redis_command_duration_seconds",
+			Help:      "The latency of the redis commands.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"command"}),
+		redisErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "redis_command_errors_total",
+			Help:      "The number of the failed redis commands, a missing key is not a failure.",
+		}, []string{"command"}),
+		disposeDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "shutdown_dispose_duration_seconds",
+			Help:      "How long the disposal of every component took.",
+		}, []string{"component"}),
+		disposeOutcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: NAMESPACE,
+			Name:      "shutdown_dispose_outcomes_total",
+			Help:      "The outcome of the disposal of every component.",
+		}, []string{"component", "outcome"}),
+		shutdownTime: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "shutdown_duration_seconds",
+			Help:      "How long the whole shutdown took.",
+		}),
+	}
+
+	ox.Registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		ox.requests,
+		ox.requestDuration,
+		ox.redisDuration,
+		ox.redisErrors,
+		ox.disposeDuration,
+		ox.disposeOutcomes,
+		ox.shutdownTime,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: NAMESPACE,
+			Name:      "http_requests_in_flight",
+			Help:      "The number of the http requests being handled.",
+		}, func() float64 {
+			return float64(tracker.Count())
+		}),
+	)
+
+	return ox
+}
+
+func (ox *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(ox.Registry, promhttp.HandlerOpts{})
+}
+
+func (ox *Metrics) ObserveRequest(method string, route string, status int, duration time.Duration) {
+	labels := prometheus.Labels{
+		"route":  route,
+		"method": method,
+		"status": strconv.Itoa(status),
+	}
+
+	ox.requests.With(labels).Inc()
+	ox.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// Instrument observes the requests of the route served by net/http.
+func (ox *Metrics) Instrument(route string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		startedAt := time.Now()
+		recorder := &statusRecorder{
+			ResponseWriter: w,
+			status:         http.StatusOK,
+		}
+
+		defer func() {
+			ox.ObserveRequest(r.Method, route, recorder.status, time.Since(startedAt))
+		}()
+
+		handler.ServeHTTP(recorder, r)
+	})
+}
+
+// ObserveShutdown records the report of the shutdown, it's meant to be called
+// before the metrics server is stopped.
+func (ox *Metrics) ObserveShutdown(report *shutdown.ShutdownReport) {
+	for _, result := range report.Results {
+		ox.disposeDuration.WithLabelValues(result.Name).Set(result.Duration.Seconds())
+		ox.disposeOutcomes.WithLabelValues(result.Name, string(result.Outcome)).Inc()
+	}
+
+	ox.shutdownTime.Set(report.Duration.Seconds())
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (ox *statusRecorder) WriteHeader(status int) {
+	if !ox.wroteHeader {
+		ox.status = status
+		ox.wroteHeader = true
+	}
+
+	ox.ResponseWriter.WriteHeader(status)
+}
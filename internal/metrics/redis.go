@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+type startedAtKey struct{}
+
+// RedisHook observes the latency and the errors of the redis commands, a
+// pipeline is observed as a whole.
+func (ox *Metrics) RedisHook() redis.Hook {
+	return redisHook{metrics: ox}
+}
+
+type redisHook struct {
+	metrics *Metrics
+}
+
+func (ox redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (ox redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	ox.observe(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (ox redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startedAtKey{}, time.Now()), nil
+}
+
+func (ox redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil && cmdErr != redis.Nil {
+			err = cmdErr
+			break
+		}
+	}
+
+	ox.observe(ctx, "pipeline", err)
+	return nil
+}
+
+func (ox redisHook) observe(ctx context.Context, command string, err error) {
+	// the after hooks are called even when an earlier hook refused the
+	// command, there's nothing to observe then.
+	startedAt, ok := ctx.Value(startedAtKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	ox.metrics.redisDuration.WithLabelValues(command).Observe(time.Since(startedAt).Seconds())
+	if err != nil && err != redis.Nil {
+		ox.metrics.redisErrors.WithLabelValues(command).Inc()
+	}
+}
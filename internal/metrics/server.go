@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+// DEFAULT_ADDRESS only listens on the loopback like the admin server, set
+// METRICS_ADDRESS, e.g. to ":9090", for a scraper outside the host.
+const DEFAULT_ADDRESS = "127.0.0.1:9090"
+
+// Config of the admin server exposing /metrics. When PushgatewayURL is set,
+// the final metrics are also pushed there on shutdown, since the process may
+// be gone before the next scrape.
+type Config struct {
+	Address        string
+	PushgatewayURL string
+	Job            string
+}
+
+// LoadConfig reads METRICS_ADDRESS, METRICS_PUSHGATEWAY_URL and METRICS_JOB.
+func LoadConfig() *Config {
+	config := &Config{
+		Address: DEFAULT_ADDRESS,
+		Job:     "graceful-shutdown",
+	}
+
+	if v, ok := os.LookupEnv("METRICS_ADDRESS"); ok {
+		config.Address = v
+	}
+	if v, ok := os.LookupEnv("METRICS_PUSHGATEWAY_URL"); ok {
+		config.PushgatewayURL = v
+	}
+	if v, ok := os.LookupEnv("METRICS_JOB"); ok {
+		config.Job = v
+	}
+
+	return config
+}
+
+// Server serves /metrics on its own port, it's meant to be stopped after
+// everything else, so the shutdown itself can be observed.
+type Server struct {
+	config  *Config
+	metrics *Metrics
+	server  *http.Server
+}
+
+func NewServer(config *Config, metrics *Metrics) *Server {
+	serverMux := http.NewServeMux()
+	serverMux.Handle("/metrics", metrics.Handler())
+
+	return &Server{
+		config:  config,
+		metrics: metrics,
+		server: &http.Server{
+			Addr:    config.Address,
+			Handler: serverMux,
+		},
+	}
+}
+
+// Start binds the address before serving, so a taken port fails right away.
+func (ox *Server) Start() error {
+	listener, err := net.Listen("tcp", ox.server.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to bind the metrics server at '%s': %w", ox.server.Addr, err)
+	}
+
+	go func() {
+		logger.Infof("metrics: server started at '%s'.", ox.server.Addr)
+		err := ox.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			logger.Errf("metrics: server stopped unexpectedly: %s", err)
+		}
+	}()
+
+	return nil
+}
+
+// Shutdown pushes the final metrics when a pushgateway is configured, then
+// stops the server.
+func (ox *Server) Shutdown(ctx context.Context) error {
+	if ox.config.PushgatewayURL != "" {
+		pusher := push.New(ox.config.PushgatewayURL, ox.config.Job).
+			Gatherer(ox.metrics.Registry).
+			Client(contextDoer{ctx: ctx})
+		if err := pusher.Push(); err != nil {
+			logger.Errf("metrics: failed to push the final metrics: %s", err)
+		}
+	}
+
+	return ox.server.Shutdown(ctx)
+}
+
+// Stop records the report of the shutdown, then shuts the server down within
+// the ctx, see shutdown.Budget.Grace.
+func (ox *Server) Stop(ctx context.Context, report *shutdown.ShutdownReport) error {
+	ox.metrics.ObserveShutdown(report)
+
+	return ox.Shutdown(ctx)
+}
+
+// contextDoer bounds the push by the shutdown context.
+type contextDoer struct {
+	ctx context.Context
+}
+
+func (ox contextDoer) Do(req *http.Request) (*http.Response, error) {
+	return http.DefaultClient.Do(req.WithContext(ox.ctx))
+}
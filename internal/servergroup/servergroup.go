@@ -0,0 +1,112 @@
+package servergroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+var ErrAlreadyStarted = errors.New("server group already started")
+
+// Group owns multiple http servers that are started and shut down together.
+type Group struct {
+	servers []*http.Server
+
+	mu      sync.Mutex
+	started bool
+	wg      sync.WaitGroup
+	errCh   chan error
+}
+
+func New(servers ...*http.Server) *Group {
+	return &Group{
+		servers: servers,
+		errCh:   make(chan error, len(servers)),
+	}
+}
+
+// Start binds every server before serving any of them, so the whole group
+// fails when one of the addresses can't be bound.
+func (ox *Group) Start() error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if ox.started {
+		return ErrAlreadyStarted
+	}
+
+	listeners := make([]net.Listener, 0, len(ox.servers))
+	for _, server := range ox.servers {
+		listener, err := net.Listen("tcp", server.Addr)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+
+			return fmt.Errorf("failed to bind server at '%s': %w", server.Addr, err)
+		}
+
+		listeners = append(listeners, listener)
+	}
+
+	ox.started = true
+	for i, server := range ox.servers {
+		ox.wg.Add(1)
+		go func(server *http.Server, listener net.Listener) {
+			defer ox.wg.Done()
+
+			logger.Infof("servergroup: server started at '%s'.", server.Addr)
+			err := server.Serve(listener)
+			if err != nil && err != http.ErrServerClosed {
+				ox.errCh <- fmt.Errorf("server at '%s' stopped unexpectedly: %w", server.Addr, err)
+			}
+		}(server, listeners[i])
+	}
+
+	return nil
+}
+
+// Err receives the error of any server that stops unexpectedly, the caller
+// is expected to shut the rest of the group down.
+func (ox *Group) Err() <-chan error {
+	return ox.errCh
+}
+
+// Shutdown shuts every server down in parallel under the same context, then
+// waits for all of them to stop serving.
+func (ox *Group) Shutdown(ctx context.Context) error {
+	var (
+		mu   sync.Mutex
+		errs shutdown.MultiError
+		wg   sync.WaitGroup
+	)
+
+	for _, server := range ox.servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+
+			if err := server.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("server at '%s': %w", server.Addr, err))
+				mu.Unlock()
+			}
+		}(server)
+	}
+
+	wg.Wait()
+	ox.wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
@@ -0,0 +1,238 @@
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+const (
+	// DEFAULT_GRACE_PERIOD follows the kubernetes' default of
+	// terminationGracePeriodSeconds.
+	DEFAULT_GRACE_PERIOD = 30 * time.Second
+
+	// GRACE_PERIOD_MARGIN is kept out of the grace period, so the shutdown
+	// can complete before the process gets killed.
+	GRACE_PERIOD_MARGIN = 2 * time.Second
+)
+
+type Phase string
+
+const (
+	PhasePreStop   Phase = "pre-stop"
+	PhaseHTTPDrain Phase = "http-drain"
+	PhaseWorkers   Phase = "workers"
+	PhaseResources Phase = "resources"
+)
+
+type PhaseWeight struct {
+	Phase  Phase
+	Weight int
+}
+
+func DefaultPhases() []PhaseWeight {
+	return []PhaseWeight{
+		{Phase: PhasePreStop, Weight: 2},
+		{Phase: PhaseHTTPDrain, Weight: 4},
+		{Phase: PhaseWorkers, Weight: 2},
+		{Phase: PhaseResources, Weight: 1},
+	}
+}
+
+// DefaultPhasesOf returns the default weights of the given phases only, for a
+// service that skips some of them, so their share isn't held back from the
+// phases that follow.
+func DefaultPhasesOf(phases ...Phase) []PhaseWeight {
+	var weights []PhaseWeight
+	for _, p := range DefaultPhases() {
+		for _, phase := range phases {
+			if p.Phase == phase {
+				weights = append(weights, p)
+				break
+			}
+		}
+	}
+
+	return weights
+}
+
+// Budget splits a single shutdown deadline into phases. Every phase gets the
+// share of its weight from the budget remaining when it begins, so the time a
+// phase doesn't use rolls forward to the next ones.
+type Budget struct {
+	Total time.Duration
+
+	mu        sync.Mutex
+	phases    []PhaseWeight
+	deadline  time.Time
+	deadlines map[Phase]time.Time
+}
+
+func NewBudget(total time.Duration, phases ...PhaseWeight) *Budget {
+	if len(phases) == 0 {
+		phases = DefaultPhases()
+	}
+
+	return &Budget{
+		Total:     total,
+		phases:    phases,
+		deadlines: make(map[Phase]time.Time),
+	}
+}
+
+// NewBudgetFromEnv reads the total budget from SHUTDOWN_TIMEOUT as a duration
+// (e.g. "25s"), or from TERMINATION_GRACE_PERIOD_SECONDS minus a safety
+// margin, falling back to the kubernetes' default grace period.
+func NewBudgetFromEnv(phases ...PhaseWeight) (*Budget, error) {
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT"); raw != "" {
+		total, err := time.ParseDuration(raw)
+		if err != nil || total <= 0 {
+			return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT '%s'", raw)
+		}
+
+		return NewBudget(total, phases...), nil
+	}
+
+	gracePeriod := DEFAULT_GRACE_PERIOD
+	if raw := os.Getenv("TERMINATION_GRACE_PERIOD_SECONDS"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid TERMINATION_GRACE_PERIOD_SECONDS '%s'", raw)
+		}
+
+		gracePeriod = time.Duration(seconds) * time.Second
+	}
+
+	total := gracePeriod - GRACE_PERIOD_MARGIN
+	if total <= 0 {
+		total = gracePeriod
+	}
+
+	return NewBudget(total, phases...), nil
+}
+
+// Start begins the countdown, it's implicitly called by the first phase.
+func (ox *Budget) Start() {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.start()
+}
+
+func (ox *Budget) start() {
+	if ox.deadline.IsZero() {
+		ox.deadline = time.Now().Add(ox.Total)
+	}
+}
+
+func (ox *Budget) Remaining() time.Duration {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if ox.deadline.IsZero() {
+		return ox.Total
+	}
+
+	if remaining := time.Until(ox.deadline); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
+
+// Context limits the ctx by the whole budget.
+func (ox *Budget) Context(ctx context.Context) (context.Context, context.CancelFunc) {
+	ox.mu.Lock()
+	ox.start()
+	deadline := ox.deadline
+	ox.mu.Unlock()
+
+	return context.WithDeadline(ctx, deadline)
+}
+
+// Grace limits the ctx by the end of the budget plus the grace period margin,
+// the only time left for the work done after the shutdown, e.g. stopping the
+// metrics and admin servers.
+func (ox *Budget) Grace(ctx context.Context) (context.Context, context.CancelFunc) {
+	ox.mu.Lock()
+	ox.start()
+	deadline := ox.deadline
+	ox.mu.Unlock()
+
+	return context.WithDeadline(ctx, deadline.Add(GRACE_PERIOD_MARGIN))
+}
+
+// Phase limits the ctx by the share of the phase. The share is taken once
+// the phase begins, the later calls for the same phase share its deadline.
+func (ox *Budget) Phase(ctx context.Context, phase Phase) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, ox.phaseDeadline(phase))
+}
+
+func (ox *Budget) phaseDeadline(phase Phase) time.Time {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.start()
+
+	if deadline, ok := ox.deadlines[phase]; ok {
+		return deadline
+	}
+
+	remaining := time.Until(ox.deadline)
+	if remaining <= 0 {
+		ox.deadlines[phase] = ox.deadline
+		return ox.deadline
+	}
+
+	// the phase takes its share from the phases that haven't begun yet,
+	// including itself.
+	weight, total := 0, 0
+	for _, p := range ox.phases {
+		if _, begun := ox.deadlines[p.Phase]; begun {
+			continue
+		}
+
+		total += p.Weight
+		if p.Phase == phase {
+			weight = p.Weight
+		}
+	}
+
+	deadline := ox.deadline
+	if weight > 0 && weight < total {
+		deadline = time.Now().Add(remaining * time.Duration(weight) / time.Duration(total))
+	}
+
+	ox.deadlines[phase] = deadline
+	return deadline
+}
+
+// Wrap limits the disposal of the disposer by the share of the phase.
+func (ox *Budget) Wrap(phase Phase, disposer component.ContextDisposableComponent) component.ContextDisposableComponent {
+	return component.DisposeContextFunc(func(ctx context.Context) error {
+		ctx, cancel := ox.Phase(ctx, phase)
+		defer cancel()
+
+		return disposer.DisposeContext(ctx)
+	})
+}
+
+// Shorten lowers the total budget, e.g. for a shutdown requested with its own
+// timeout. It has no effect once the countdown has begun or when the total is
+// already shorter.
+func (ox *Budget) Shorten(total time.Duration) bool {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if !ox.deadline.IsZero() || total <= 0 || total >= ox.Total {
+		return false
+	}
+
+	ox.Total = total
+	return true
+}
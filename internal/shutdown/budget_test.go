@@ -0,0 +1,161 @@
+package shutdown
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+// tolerance absorbs the time passing between taking a share and checking it.
+const tolerance = 20 * time.Millisecond
+
+func assertWithin(t *testing.T, phase Phase, got time.Duration, want time.Duration) {
+	t.Helper()
+
+	if got > want || got < want-tolerance {
+		t.Errorf("share of '%s' = %s, want %s", phase, got, want)
+	}
+}
+
+func shareOf(budget *Budget, phase Phase) time.Duration {
+	return time.Until(budget.phaseDeadline(phase))
+}
+
+func TestBudgetPhaseShares(t *testing.T) {
+	budget := NewBudget(900 * time.Millisecond)
+
+	// the weights are 2, 4, 2 and 1, out of 9.
+	assertWithin(t, PhasePreStop, shareOf(budget, PhasePreStop), 200*time.Millisecond)
+
+	// the http drain takes 4 out of the remaining 7, of the budget left
+	// whether or not the pre-stop used its share.
+	remaining := budget.Remaining()
+	assertWithin(t, PhaseHTTPDrain, shareOf(budget, PhaseHTTPDrain), remaining*4/7)
+
+	// the later calls of a phase share its deadline.
+	first := budget.phaseDeadline(PhaseHTTPDrain)
+	if second := budget.phaseDeadline(PhaseHTTPDrain); !second.Equal(first) {
+		t.Errorf("second deadline of '%s' = %s, want %s", PhaseHTTPDrain, second, first)
+	}
+
+	// the last phase gets whatever is left.
+	budget.phaseDeadline(PhaseWorkers)
+	if got := budget.phaseDeadline(PhaseResources); !got.Equal(budget.deadline) {
+		t.Errorf("deadline of '%s' = %s, want the budget deadline %s", PhaseResources, got, budget.deadline)
+	}
+}
+
+func TestBudgetUnusedShareRollsForward(t *testing.T) {
+	budget := NewBudget(400*time.Millisecond, PhaseWeight{Phase: PhaseHTTPDrain, Weight: 1}, PhaseWeight{Phase: PhaseWorkers, Weight: 1})
+
+	assertWithin(t, PhaseHTTPDrain, shareOf(budget, PhaseHTTPDrain), 200*time.Millisecond)
+
+	// the http drain finished right away, so the workers get the whole budget
+	// left rather than only their half.
+	assertWithin(t, PhaseWorkers, shareOf(budget, PhaseWorkers), 400*time.Millisecond)
+}
+
+func TestDefaultPhasesOf(t *testing.T) {
+	budget := NewBudget(700*time.Millisecond, DefaultPhasesOf(PhasePreStop, PhaseHTTPDrain, PhaseResources)...)
+
+	// the weights are 2, 4 and 1, out of 7, the workers hold nothing back.
+	assertWithin(t, PhasePreStop, shareOf(budget, PhasePreStop), 200*time.Millisecond)
+
+	remaining := budget.Remaining()
+	assertWithin(t, PhaseHTTPDrain, shareOf(budget, PhaseHTTPDrain), remaining*4/5)
+
+	if got := budget.phaseDeadline(PhaseResources); !got.Equal(budget.deadline) {
+		t.Errorf("deadline of '%s' = %s, want the budget deadline %s", PhaseResources, got, budget.deadline)
+	}
+}
+
+func TestBudgetWrap(t *testing.T) {
+	budget := NewBudget(100*time.Millisecond, PhaseWeight{Phase: PhaseWorkers, Weight: 1}, PhaseWeight{Phase: PhaseResources, Weight: 1})
+
+	var deadline time.Time
+	disposer := budget.Wrap(PhaseWorkers, component.DisposeContextFunc(func(ctx context.Context) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	}))
+	if err := disposer.DisposeContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	assertWithin(t, PhaseWorkers, time.Until(deadline), 50*time.Millisecond)
+}
+
+func TestBudgetShorten(t *testing.T) {
+	budget := NewBudget(10 * time.Second)
+
+	if budget.Shorten(20 * time.Second) {
+		t.Error("the budget has been lengthened")
+	}
+
+	if !budget.Shorten(5 * time.Second) {
+		t.Error("the budget has not been shortened")
+	}
+
+	budget.Start()
+	if budget.Shorten(time.Second) {
+		t.Error("the budget has been shortened after its countdown began")
+	}
+
+	if budget.Total != 5*time.Second {
+		t.Errorf("total = %s, want 5s", budget.Total)
+	}
+}
+
+func TestNewBudgetFromEnv(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     string
+		gracePeriod string
+		want        time.Duration
+		wantErr     bool
+	}{
+		{name: "default", want: DEFAULT_GRACE_PERIOD - GRACE_PERIOD_MARGIN},
+		{name: "shutdown timeout", timeout: "25s", gracePeriod: "60", want: 25 * time.Second},
+		{name: "grace period", gracePeriod: "60", want: 60*time.Second - GRACE_PERIOD_MARGIN},
+		{name: "grace period within the margin", gracePeriod: "1", want: time.Second},
+		{name: "invalid shutdown timeout", timeout: "soon", wantErr: true},
+		{name: "invalid grace period", gracePeriod: "-1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SHUTDOWN_TIMEOUT", tt.timeout)
+			t.Setenv("TERMINATION_GRACE_PERIOD_SECONDS", tt.gracePeriod)
+
+			budget, err := NewBudgetFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", budget.Total)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if budget.Total != tt.want {
+				t.Errorf("total = %s, want %s", budget.Total, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetGrace(t *testing.T) {
+	budget := NewBudget(100 * time.Millisecond)
+	budget.Start()
+
+	ctx, cancel := budget.Grace(context.Background())
+	defer cancel()
+
+	deadline, _ := ctx.Deadline()
+	if want := budget.deadline.Add(GRACE_PERIOD_MARGIN); !deadline.Equal(want) {
+		t.Errorf("deadline = %s, want %s", deadline, want)
+	}
+}
@@ -0,0 +1,22 @@
+package shutdown
+
+import (
+	"os"
+)
+
+// The exit codes let the supervisors tell how the shutdown went.
+const (
+	ExitCodeOK           = 0
+	ExitCodeDisposeError = 1
+	ExitCodeTimeout      = 2
+	ExitCodeForced       = 3
+)
+
+// Exit flushes the standard outputs before exiting with the given code, so
+// the last log lines are not lost.
+func Exit(code int) {
+	_ = os.Stdout.Sync()
+	_ = os.Stderr.Sync()
+
+	os.Exit(code)
+}
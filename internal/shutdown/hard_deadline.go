@@ -0,0 +1,73 @@
+package shutdown
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+)
+
+// HardDeadline is the server-wide context of the requests, it's cancelled
+// once the drain budget is exhausted, so the handlers still running abort
+// instead of touching the resources that are being closed.
+type HardDeadline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	once   sync.Once
+}
+
+func NewHardDeadline() *HardDeadline {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HardDeadline{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (ox *HardDeadline) Context() context.Context {
+	return ox.ctx
+}
+
+// BaseContext is meant for http.Server.BaseContext, every request context of
+// the server derives from the hard deadline.
+func (ox *HardDeadline) BaseContext(net.Listener) context.Context {
+	return ox.ctx
+}
+
+func (ox *HardDeadline) Expire() {
+	ox.once.Do(func() {
+		logger.Infof("shutdown: the drain budget is exhausted, aborting the requests still running.")
+		ox.cancel()
+	})
+}
+
+func (ox *HardDeadline) IsExpired() bool {
+	return ox.ctx.Err() != nil
+}
+
+// Bind derives a context that is also cancelled by the hard deadline, for
+// the requests whose context can't derive from it.
+func (ox *HardDeadline) Bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ox.ctx.Done():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Shutdown runs the shutdown of a server, then expires the hard deadline when
+// the ctx is done before the requests complete.
+func (ox *HardDeadline) Shutdown(ctx context.Context, shutdown func(ctx context.Context) error) error {
+	err := shutdown(ctx)
+	if err != nil && ctx.Err() != nil {
+		ox.Expire()
+	}
+
+	return err
+}
@@ -0,0 +1,129 @@
+package shutdown
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateDisposing State = "disposing"
+	StateDisposed  State = "disposed"
+	StateFailed    State = "failed"
+)
+
+// Progress is the live state of a single component, as seen by the recorder
+// while the shutdown is going on.
+type Progress struct {
+	Name      string
+	State     State
+	StartedAt time.Time
+	Duration  time.Duration
+	Outcome   Outcome
+	Err       error
+}
+
+func (ox Progress) MarshalJSON() ([]byte, error) {
+	payload := struct {
+		Name      string     `json:"name"`
+		State     State      `json:"state"`
+		StartedAt *time.Time `json:"started_at,omitempty"`
+		Duration  string     `json:"duration,omitempty"`
+		Outcome   Outcome    `json:"outcome,omitempty"`
+		Error     string     `json:"error,omitempty"`
+	}{
+		Name:    ox.Name,
+		State:   ox.State,
+		Outcome: ox.Outcome,
+	}
+	if !ox.StartedAt.IsZero() {
+		payload.StartedAt = &ox.StartedAt
+		payload.Duration = ox.Duration.String()
+	}
+	if ox.Err != nil {
+		payload.Error = ox.Err.Error()
+	}
+
+	return json.Marshal(payload)
+}
+
+// Expect lists the names before they are run, so they show up as pending in
+// the progress.
+func (ox *Recorder) Expect(names ...string) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	for _, name := range names {
+		if !ox.isExpected(name) {
+			ox.expected = append(ox.expected, name)
+		}
+	}
+}
+
+func (ox *Recorder) isExpected(name string) bool {
+	for _, expected := range ox.expected {
+		if expected == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Progress returns the state of every expected, running or finished
+// component, in the order they are first known to the recorder.
+func (ox *Recorder) Progress() []Progress {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	now := time.Now()
+	progress := make([]Progress, 0, len(ox.expected))
+	index := make(map[string]int, len(ox.expected))
+	lookup := func(name string) *Progress {
+		if i, ok := index[name]; ok {
+			return &progress[i]
+		}
+
+		index[name] = len(progress)
+		progress = append(progress, Progress{Name: name, State: StatePending})
+		return &progress[len(progress)-1]
+	}
+
+	for _, name := range ox.expected {
+		lookup(name)
+	}
+
+	for _, res := range ox.results {
+		p := lookup(res.Name)
+		p.StartedAt = res.StartedAt
+		p.Duration = res.Duration
+		p.Outcome = res.Outcome
+		p.Err = res.Err
+
+		p.State = StateDisposed
+		if res.Outcome != OutcomeOK {
+			p.State = StateFailed
+		}
+	}
+
+	// the unexpected running names are appended in order, rather than in the
+	// random order of the map.
+	running := make([]string, 0, len(ox.running))
+	for name := range ox.running {
+		running = append(running, name)
+	}
+	sort.Strings(running)
+
+	for _, name := range running {
+		startedAt := ox.running[name]
+		p := lookup(name)
+		p.State = StateDisposing
+		p.StartedAt = startedAt
+		p.Duration = now.Sub(startedAt)
+	}
+
+	return progress
+}
@@ -0,0 +1,270 @@
+package shutdown
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+type Outcome string
+
+const (
+	OutcomeOK      Outcome = "ok"
+	OutcomeError   Outcome = "error"
+	OutcomeTimeout Outcome = "timeout"
+	OutcomePanic   Outcome = "panic"
+	OutcomeAborted Outcome = "aborted"
+)
+
+// Result is the disposal result of a single component or hook.
+type Result struct {
+	Name      string
+	StartedAt time.Time
+	Duration  time.Duration
+	Outcome   Outcome
+	Err       error
+}
+
+func (ox Result) MarshalJSON() ([]byte, error) {
+	payload := struct {
+		Name      string    `json:"name"`
+		StartedAt time.Time `json:"started_at"`
+		Duration  string    `json:"duration"`
+		Outcome   Outcome   `json:"outcome"`
+		Error     string    `json:"error,omitempty"`
+	}{
+		Name:      ox.Name,
+		StartedAt: ox.StartedAt,
+		Duration:  ox.Duration.String(),
+		Outcome:   ox.Outcome,
+	}
+	if ox.Err != nil {
+		payload.Error = ox.Err.Error()
+	}
+
+	return json.Marshal(payload)
+}
+
+type ShutdownReport struct {
+	StartedAt time.Time
+	Duration  time.Duration
+	Results   []Result
+}
+
+func (ox *ShutdownReport) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		StartedAt time.Time `json:"started_at"`
+		Duration  string    `json:"duration"`
+		Results   []Result  `json:"results"`
+	}{
+		StartedAt: ox.StartedAt,
+		Duration:  ox.Duration.String(),
+		Results:   ox.Results,
+	})
+}
+
+// Err returns the errors of the failed results joined as a MultiError, or
+// nil when everything has been disposed successfully.
+func (ox *ShutdownReport) Err() error {
+	var errs MultiError
+	for _, res := range ox.Results {
+		if res.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.Name, res.Err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
+
+// ExitCode maps the report into the process exit code, the most severe
+// outcome wins.
+func (ox *ShutdownReport) ExitCode() int {
+	code := ExitCodeOK
+	for _, res := range ox.Results {
+		switch res.Outcome {
+		case OutcomeAborted:
+			return ExitCodeForced
+		case OutcomeTimeout:
+			code = ExitCodeTimeout
+		case OutcomeError, OutcomePanic:
+			if code == ExitCodeOK {
+				code = ExitCodeDisposeError
+			}
+		}
+	}
+
+	return code
+}
+
+// Log emits the report as a single JSON log line.
+func (ox *ShutdownReport) Log() {
+	raw, err := json.Marshal(ox)
+	if err != nil {
+		logger.Errf("failed to encode the shutdown report: %+v", err)
+		return
+	}
+
+	logger.Info(string(raw))
+}
+
+type MultiError []error
+
+func (ox MultiError) Error() string {
+	messages := make([]string, 0, len(ox))
+	for _, err := range ox {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (ox MultiError) Unwrap() []error {
+	return ox
+}
+
+// Is matches any of the errors, since errors.Is only walks Unwrap() []error
+// from Go 1.20 on.
+func (ox MultiError) Is(target error) bool {
+	for _, err := range ox {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first of the errors matching the target, see Is.
+func (ox MultiError) As(target interface{}) bool {
+	for _, err := range ox {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Recorder keeps track of the disposal results to build a ShutdownReport,
+// it's safe to be used by concurrent hooks.
+type Recorder struct {
+	mu       sync.Mutex
+	results  []Result
+	expected []string
+	running  map[string]time.Time
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		running: make(map[string]time.Time),
+	}
+}
+
+// Run calls the fn and records its result under the given name, a panic
+// raised by the fn is recovered and recorded as an error.
+func (ox *Recorder) Run(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	res := Result{
+		Name:      name,
+		StartedAt: time.Now(),
+		Outcome:   OutcomeOK,
+	}
+
+	ox.mu.Lock()
+	ox.running[name] = res.StartedAt
+	ox.mu.Unlock()
+
+	res.Err = component.SafeCall(func() error { return fn(ctx) })
+	res.Duration = time.Since(res.StartedAt)
+
+	var panicErr *component.PanicError
+	switch {
+	case res.Err == nil:
+	case errors.As(res.Err, &panicErr):
+		res.Outcome = OutcomePanic
+	case errors.Is(res.Err, ErrAborted):
+		res.Outcome = OutcomeAborted
+	case errors.Is(res.Err, component.ErrDisposeTimeout), errors.Is(res.Err, context.DeadlineExceeded):
+		res.Outcome = OutcomeTimeout
+	default:
+		res.Outcome = OutcomeError
+	}
+
+	ox.mu.Lock()
+	delete(ox.running, name)
+	ox.results = append(ox.results, res)
+	ox.mu.Unlock()
+
+	return res.Err
+}
+
+// Skip records the named component as aborted without running it.
+func (ox *Recorder) Skip(name string, err error) error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.results = append(ox.results, Result{
+		Name:      name,
+		StartedAt: time.Now(),
+		Outcome:   OutcomeAborted,
+		Err:       err,
+	})
+
+	return err
+}
+
+// Hook wraps the fn as a termination hook that records its result, the error
+// is only logged since the hook has nowhere to return it. The name is expected
+// from the moment the hook is created.
+func (ox *Recorder) Hook(name string, fn func(ctx context.Context) error) func(ctx context.Context) {
+	ox.Expect(name)
+
+	return func(ctx context.Context) {
+		if err := ox.Run(ctx, name, fn); err != nil {
+			logger.Errf("error during disposing '%s': %+v", name, err)
+		}
+	}
+}
+
+func (ox *Recorder) Report() *ShutdownReport {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	report := &ShutdownReport{
+		Results: make([]Result, len(ox.results)),
+	}
+	copy(report.Results, ox.results)
+
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].StartedAt.Before(report.Results[j].StartedAt)
+	})
+
+	var finishedAt time.Time
+	for i, res := range report.Results {
+		if i == 0 {
+			report.StartedAt = res.StartedAt
+		}
+
+		if end := res.StartedAt.Add(res.Duration); end.After(finishedAt) {
+			finishedAt = end
+		}
+	}
+
+	if !finishedAt.IsZero() {
+		report.Duration = finishedAt.Sub(report.StartedAt)
+	}
+
+	return report
+}
@@ -0,0 +1,72 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+func TestMultiErrorIsAs(t *testing.T) {
+	errs := MultiError{
+		fmt.Errorf("worker: %w", component.ErrDisposeTimeout),
+		fmt.Errorf("database: %w", component.NewPanicError("database is broken")),
+	}
+
+	if !errors.Is(errs, component.ErrDisposeTimeout) {
+		t.Errorf("errors.Is(%v) = false, want true", component.ErrDisposeTimeout)
+	}
+
+	if errors.Is(errs, ErrAborted) {
+		t.Errorf("errors.Is(%v) = true, want false", ErrAborted)
+	}
+
+	var panicErr *component.PanicError
+	if !errors.As(errs, &panicErr) || panicErr.Value != "database is broken" {
+		t.Errorf("errors.As(*PanicError) = %v, want the database panic", panicErr)
+	}
+}
+
+func TestShutdownReportExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		want     int
+	}{
+		{name: "ok", outcomes: []Outcome{OutcomeOK, OutcomeOK}, want: ExitCodeOK},
+		{name: "error", outcomes: []Outcome{OutcomeOK, OutcomePanic}, want: ExitCodeDisposeError},
+		{name: "timeout", outcomes: []Outcome{OutcomeError, OutcomeTimeout}, want: ExitCodeTimeout},
+		{name: "aborted", outcomes: []Outcome{OutcomeTimeout, OutcomeAborted}, want: ExitCodeForced},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &ShutdownReport{}
+			for _, outcome := range tt.outcomes {
+				report.Results = append(report.Results, Result{Outcome: outcome})
+			}
+
+			if got := report.ExitCode(); got != tt.want {
+				t.Errorf("exit code = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderRecoversPanic(t *testing.T) {
+	recorder := NewRecorder()
+	err := recorder.Run(context.Background(), "worker", func(ctx context.Context) error {
+		panic("worker is broken")
+	})
+
+	var panicErr *component.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("got %v, want a PanicError", err)
+	}
+
+	if outcome := recorder.Report().Results[0].Outcome; outcome != OutcomePanic {
+		t.Errorf("outcome = %s, want %s", outcome, OutcomePanic)
+	}
+}
@@ -0,0 +1,285 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+var (
+	ErrEmptyName         = errors.New("component name cannot be empty")
+	ErrDuplicateName     = errors.New("component already registered")
+	ErrUnknownComponent  = errors.New("unknown component")
+	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrCyclicDependency  = errors.New("cyclic dependency")
+	ErrAborted           = errors.New("shutdown aborted")
+)
+
+type node struct {
+	name         string
+	disposer     component.ContextDisposableComponent
+	timeout      time.Duration
+	critical     bool
+	dependencies []string
+	dependents   []string
+}
+
+// Orchestrator disposes registered components in reverse dependency order,
+// a component is only disposed after everything that depends on it has been
+// disposed, while independent components are disposed in parallel.
+type Orchestrator struct {
+	mu             sync.Mutex
+	nodes          map[string]*node
+	names          []string
+	defaultTimeout time.Duration
+	recorder       *Recorder
+
+	abortCh   chan struct{}
+	abortOnce sync.Once
+
+	// report is kept from the first shutdown, so the components are only
+	// disposed once.
+	report    *ShutdownReport
+	reportErr error
+}
+
+func New() *Orchestrator {
+	return NewWithRecorder(NewRecorder())
+}
+
+// NewWithRecorder records the disposal results into the given recorder, e.g.
+// one shared with the admin server before the orchestrator is built.
+func NewWithRecorder(recorder *Recorder) *Orchestrator {
+	return &Orchestrator{
+		nodes:    make(map[string]*node),
+		recorder: recorder,
+		abortCh:  make(chan struct{}),
+	}
+}
+
+// Recorder returns the recorder of the disposal results, every registered
+// component is expected by it, so the shutdown progress can be followed.
+func (ox *Orchestrator) Recorder() *Recorder {
+	return ox.recorder
+}
+
+// Register adds the disposer under the given name, the dependencies must be
+// registered beforehand.
+func (ox *Orchestrator) Register(name string, disposer component.DisposableComponent, dependencies ...string) error {
+	return ox.RegisterContext(name, component.WithContext(disposer), dependencies...)
+}
+
+// RegisterContext is like Register, but for a disposer that honors the
+// context deadline.
+func (ox *Orchestrator) RegisterContext(name string, disposer component.ContextDisposableComponent, dependencies ...string) error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if name == "" {
+		return ErrEmptyName
+	}
+
+	if _, ok := ox.nodes[name]; ok {
+		return fmt.Errorf("%w: '%s'", ErrDuplicateName, name)
+	}
+
+	for _, dep := range dependencies {
+		// since every dependency must already be registered, a self-reference
+		// is the only way to form a cycle.
+		if dep == name {
+			return fmt.Errorf("%w: '%s' depends on itself", ErrCyclicDependency, name)
+		}
+
+		if _, ok := ox.nodes[dep]; !ok {
+			return fmt.Errorf("%w: '%s' required by '%s'", ErrUnknownDependency, dep, name)
+		}
+	}
+
+	ox.nodes[name] = &node{
+		name:         name,
+		disposer:     disposer,
+		dependencies: dependencies,
+	}
+	ox.names = append(ox.names, name)
+	ox.recorder.Expect(name)
+
+	for _, dep := range dependencies {
+		ox.nodes[dep].dependents = append(ox.nodes[dep].dependents, name)
+	}
+
+	return nil
+}
+
+// SetDefaultTimeout limits the disposal time of the components without their
+// own timeout, zero means no limit other than the shutdown context.
+func (ox *Orchestrator) SetDefaultTimeout(timeout time.Duration) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.defaultTimeout = timeout
+}
+
+// SetTimeout limits the disposal time of the named component.
+func (ox *Orchestrator) SetTimeout(name string, timeout time.Duration) error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	n, ok := ox.nodes[name]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrUnknownComponent, name)
+	}
+
+	n.timeout = timeout
+	return nil
+}
+
+// SetCritical marks the named component to be disposed even when the
+// shutdown is aborted.
+func (ox *Orchestrator) SetCritical(name string) error {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	n, ok := ox.nodes[name]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrUnknownComponent, name)
+	}
+
+	n.critical = true
+	return nil
+}
+
+// Abort cuts the running shutdown short, the non-critical components that
+// are still being disposed are cancelled and the remaining ones are skipped.
+func (ox *Orchestrator) Abort() {
+	ox.abortOnce.Do(func() {
+		logger.Errf("shutdown: aborted, skipping the non-critical components.")
+		close(ox.abortCh)
+	})
+}
+
+func (ox *Orchestrator) IsAborted() bool {
+	select {
+	case <-ox.abortCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown disposes every registered component and returns the report along
+// with the joined errors of the failed ones. A failed or timed out component
+// doesn't block its dependencies from being disposed. The later calls return
+// the report of the first one.
+func (ox *Orchestrator) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	if ox.report != nil {
+		return ox.report, ox.reportErr
+	}
+
+	type result struct {
+		name string
+		err  error
+	}
+
+	recorder := ox.recorder
+
+	// the non-critical components are disposed under a context that is
+	// cancelled once the shutdown is aborted.
+	abortCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-ox.abortCh:
+			cancel()
+		case <-abortCtx.Done():
+		}
+	}()
+
+	pending := make(map[string]int, len(ox.nodes))
+	for _, name := range ox.names {
+		pending[name] = len(ox.nodes[name].dependents)
+	}
+
+	results := make(chan result, len(ox.nodes))
+	dispose := func(n *node) {
+		go func() {
+			if !n.critical && ox.IsAborted() {
+				results <- result{
+					name: n.name,
+					err:  recorder.Skip(n.name, ErrAborted),
+				}
+				return
+			}
+
+			nodeCtx := ctx
+			if !n.critical {
+				nodeCtx = abortCtx
+			}
+
+			logger.Infof("shutdown: disposing '%s'...", n.name)
+			results <- result{
+				name: n.name,
+				err: recorder.Run(nodeCtx, n.name, func(ctx context.Context) error {
+					return ox.dispose(ctx, n)
+				}),
+			}
+		}()
+	}
+
+	for _, name := range ox.names {
+		if pending[name] == 0 {
+			dispose(ox.nodes[name])
+		}
+	}
+
+	for i := 0; i < len(ox.nodes); i++ {
+		res := <-results
+		if res.err != nil {
+			logger.Errf("shutdown: error during disposing '%s': %+v", res.name, res.err)
+		}
+
+		for _, dep := range ox.nodes[res.name].dependencies {
+			pending[dep]--
+			if pending[dep] == 0 {
+				dispose(ox.nodes[dep])
+			}
+		}
+	}
+
+	ox.report = recorder.Report()
+	ox.reportErr = ox.report.Err()
+	return ox.report, ox.reportErr
+}
+
+func (ox *Orchestrator) dispose(ctx context.Context, n *node) error {
+	timeout := n.timeout
+	if timeout <= 0 {
+		timeout = ox.defaultTimeout
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := n.disposer.DisposeContext(ctx)
+	switch {
+	case err == nil:
+	case ctx.Err() == context.DeadlineExceeded:
+		return fmt.Errorf("%w: %v", component.ErrDisposeTimeout, err)
+	case ctx.Err() == context.Canceled && ox.IsAborted():
+		return fmt.Errorf("%w: %v", ErrAborted, err)
+	}
+
+	return err
+}
@@ -0,0 +1,271 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+// sequence records the names in the order they're disposed.
+type sequence struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (ox *sequence) disposer(name string) component.ContextDisposableComponent {
+	return component.DisposeContextFunc(func(ctx context.Context) error {
+		ox.mu.Lock()
+		defer ox.mu.Unlock()
+
+		ox.names = append(ox.names, name)
+		return nil
+	})
+}
+
+func (ox *sequence) Names() []string {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return append([]string(nil), ox.names...)
+}
+
+func blockUntilDone(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func resultOf(t *testing.T, report *ShutdownReport, name string) Result {
+	t.Helper()
+
+	for _, res := range report.Results {
+		if res.Name == name {
+			return res
+		}
+	}
+
+	t.Fatalf("no result of '%s' in %+v", name, report.Results)
+	return Result{}
+}
+
+func TestOrchestratorShutdownOrder(t *testing.T) {
+	seq := &sequence{}
+	orchestrator := New()
+
+	// the server uses the workers, which use the database.
+	mustRegister(t, orchestrator, "database", seq.disposer("database"))
+	mustRegister(t, orchestrator, "worker", seq.disposer("worker"), "database")
+	mustRegister(t, orchestrator, "server", seq.disposer("server"), "worker")
+
+	report, err := orchestrator.Shutdown(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	want := []string{"server", "worker", "database"}
+	if got := seq.Names(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("disposal order = %v, want %v", got, want)
+	}
+
+	if len(report.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(report.Results), len(want))
+	}
+}
+
+func TestOrchestratorParallelSiblings(t *testing.T) {
+	orchestrator := New()
+
+	// each sibling waits for the other one to begin, so they only finish when
+	// they're disposed in parallel.
+	var running int32
+	sibling := component.DisposeContextFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&running, 1)
+		for atomic.LoadInt32(&running) < 2 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Millisecond):
+			}
+		}
+
+		return nil
+	})
+
+	mustRegister(t, orchestrator, "database", component.DisposeContextFunc(func(ctx context.Context) error {
+		if n := atomic.LoadInt32(&running); n != 2 {
+			t.Errorf("database disposed with %d of its dependents, want 2", n)
+		}
+		return nil
+	}))
+	mustRegister(t, orchestrator, "worker-1", sibling, "database")
+	mustRegister(t, orchestrator, "worker-2", sibling, "database")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if _, err := orchestrator.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+}
+
+func TestOrchestratorRegisterErrors(t *testing.T) {
+	nop := component.DisposeContextFunc(func(ctx context.Context) error { return nil })
+
+	tests := []struct {
+		name         string
+		component    string
+		dependencies []string
+		want         error
+	}{
+		{name: "empty name", component: "", want: ErrEmptyName},
+		{name: "duplicate name", component: "database", want: ErrDuplicateName},
+		{name: "unknown dependency", component: "worker", dependencies: []string{"cache"}, want: ErrUnknownDependency},
+		{name: "self dependency", component: "worker", dependencies: []string{"worker"}, want: ErrCyclicDependency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orchestrator := New()
+			mustRegister(t, orchestrator, "database", nop)
+
+			err := orchestrator.RegisterContext(tt.component, nop, tt.dependencies...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrchestratorTimeout(t *testing.T) {
+	orchestrator := New()
+	orchestrator.SetDefaultTimeout(20 * time.Millisecond)
+
+	mustRegister(t, orchestrator, "database", component.DisposeContextFunc(blockUntilDone))
+	mustRegister(t, orchestrator, "worker", component.DisposeContextFunc(blockUntilDone), "database")
+	if err := orchestrator.SetTimeout("worker", 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	report, err := orchestrator.Shutdown(ctx)
+	if !errors.Is(err, component.ErrDisposeTimeout) {
+		t.Fatalf("got %v, want %v", err, component.ErrDisposeTimeout)
+	}
+
+	for _, name := range []string{"worker", "database"} {
+		if res := resultOf(t, report, name); res.Outcome != OutcomeTimeout {
+			t.Errorf("outcome of '%s' = %s, want %s", name, res.Outcome, OutcomeTimeout)
+		}
+	}
+
+	// the worker has its own timeout, shorter than the default one.
+	if res := resultOf(t, report, "worker"); res.Duration >= 20*time.Millisecond {
+		t.Errorf("worker took %s, want less than the default timeout", res.Duration)
+	}
+}
+
+func TestOrchestratorPanic(t *testing.T) {
+	seq := &sequence{}
+	orchestrator := New()
+
+	mustRegister(t, orchestrator, "database", seq.disposer("database"))
+	mustRegister(t, orchestrator, "worker", component.DisposeContextFunc(func(ctx context.Context) error {
+		panic("worker is broken")
+	}), "database")
+
+	report, err := orchestrator.Shutdown(context.Background())
+
+	var panicErr *component.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("got %v, want a PanicError", err)
+	}
+
+	if res := resultOf(t, report, "worker"); res.Outcome != OutcomePanic {
+		t.Errorf("outcome of 'worker' = %s, want %s", res.Outcome, OutcomePanic)
+	}
+
+	// the panic doesn't keep the dependency from being disposed.
+	if got := seq.Names(); !reflect.DeepEqual(got, []string{"database"}) {
+		t.Errorf("disposed %v, want [database]", got)
+	}
+}
+
+func TestOrchestratorAbort(t *testing.T) {
+	seq := &sequence{}
+	orchestrator := New()
+
+	started := make(chan struct{})
+	mustRegister(t, orchestrator, "database", seq.disposer("database"))
+	mustRegister(t, orchestrator, "cache", seq.disposer("cache"), "database")
+	mustRegister(t, orchestrator, "worker", component.DisposeContextFunc(func(ctx context.Context) error {
+		close(started)
+		return blockUntilDone(ctx)
+	}), "cache")
+	if err := orchestrator.SetCritical("database"); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		<-started
+		orchestrator.Abort()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	report, err := orchestrator.Shutdown(ctx)
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("got %v, want %v", err, ErrAborted)
+	}
+
+	// the running worker is cancelled, the cache is skipped, while the
+	// critical database is still disposed.
+	for _, name := range []string{"worker", "cache"} {
+		if res := resultOf(t, report, name); res.Outcome != OutcomeAborted {
+			t.Errorf("outcome of '%s' = %s, want %s", name, res.Outcome, OutcomeAborted)
+		}
+	}
+
+	if res := resultOf(t, report, "database"); res.Outcome != OutcomeOK {
+		t.Errorf("outcome of 'database' = %s, want %s", res.Outcome, OutcomeOK)
+	}
+
+	if got := seq.Names(); !reflect.DeepEqual(got, []string{"database"}) {
+		t.Errorf("disposed %v, want [database]", got)
+	}
+}
+
+func TestOrchestratorShutdownOnce(t *testing.T) {
+	var calls int32
+	orchestrator := New()
+	mustRegister(t, orchestrator, "database", component.DisposeContextFunc(func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}))
+
+	first, _ := orchestrator.Shutdown(context.Background())
+	second, _ := orchestrator.Shutdown(context.Background())
+
+	if calls != 1 {
+		t.Errorf("disposed %d times, want once", calls)
+	}
+
+	if first != second || len(second.Results) != 1 {
+		t.Errorf("second report = %+v, want the first one", second.Results)
+	}
+}
+
+func mustRegister(t *testing.T, orchestrator *Orchestrator, name string, disposer component.ContextDisposableComponent, dependencies ...string) {
+	t.Helper()
+
+	if err := orchestrator.RegisterContext(name, disposer, dependencies...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,216 @@
+package signals
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+type Action int
+
+const (
+	ActionIgnore Action = iota
+	ActionGracefulShutdown
+	ActionImmediateShutdown
+	ActionReload
+	ActionDumpGoroutines
+)
+
+func (ox Action) String() string {
+	switch ox {
+	case ActionGracefulShutdown:
+		return "graceful-shutdown"
+	case ActionImmediateShutdown:
+		return "immediate-shutdown"
+	case ActionReload:
+		return "reload"
+	case ActionDumpGoroutines:
+		return "dump-goroutines"
+	default:
+		return "ignore"
+	}
+}
+
+// Policy maps each signal to its action, the signals not listed are left to
+// the Go runtime defaults.
+type Policy map[os.Signal]Action
+
+// DefaultPolicy shuts down gracefully on SIGINT and SIGTERM, reloads on SIGHUP
+// and dumps the goroutines on SIGQUIT. SIGKILL is never listed since it can't
+// be caught.
+func DefaultPolicy() Policy {
+	return Policy{
+		syscall.SIGINT:  ActionGracefulShutdown,
+		syscall.SIGTERM: ActionGracefulShutdown,
+		syscall.SIGHUP:  ActionReload,
+		syscall.SIGQUIT: ActionDumpGoroutines,
+	}
+}
+
+func (ox Policy) Signals() []os.Signal {
+	sigs := make([]os.Signal, 0, len(ox))
+	for sig := range ox {
+		sigs = append(sigs, sig)
+	}
+
+	return sigs
+}
+
+// Handler dispatches the received signals according to its policy. The first
+// shutdown signal is delivered through Shutdown, the second one escalates into
+// Abort and the third one into ForceExit.
+type Handler struct {
+	Policy Policy
+
+	// Reload is called on ActionReload, the signal is ignored when it's nil.
+	Reload func()
+
+	// Abort is called on the second shutdown signal to cut the shutdown
+	// short, the second signal escalates into ForceExit when it's nil.
+	Abort func(sig os.Signal)
+
+	// ForceExit is called when the shutdown can't wait any longer, it
+	// defaults to flush the logs and exit with shutdown.ExitCodeForced.
+	ForceExit func(sig os.Signal)
+
+	// DumpOutput receives the goroutine dump, it defaults to stderr.
+	DumpOutput io.Writer
+
+	shutdownCh    chan Action
+	shutdownOnce  sync.Once
+	shutdownCount int32
+}
+
+func NewHandler(policy Policy) *Handler {
+	return &Handler{
+		Policy:     policy,
+		shutdownCh: make(chan Action, 1),
+	}
+}
+
+// Shutdown receives the action of the first shutdown signal, or of the
+// shutdown requested beforehand.
+func (ox *Handler) Shutdown() <-chan Action {
+	return ox.shutdownCh
+}
+
+// RequestShutdown starts the graceful shutdown like the first shutdown signal
+// without counting as one, e.g. for the admin trigger, so a termination signal
+// received afterwards doesn't abort it.
+func (ox *Handler) RequestShutdown() {
+	if ox.deliver(ActionGracefulShutdown) {
+		logger.Info("signals: the shutdown has been requested.")
+	}
+}
+
+// deliver sends the action through Shutdown once, it reports whether the
+// action has been sent.
+func (ox *Handler) deliver(action Action) (delivered bool) {
+	ox.shutdownOnce.Do(func() {
+		ox.shutdownCh <- action
+		delivered = true
+	})
+
+	return delivered
+}
+
+// Notify subscribes to the signals of the policy and handles them in the
+// background until the returned stop func is called.
+func (ox *Handler) Notify() (stop func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, ox.Policy.Signals()...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go ox.Run(ctx, sigs)
+
+	return func() {
+		signal.Stop(sigs)
+		cancel()
+	}
+}
+
+// Run handles the signals received from the channel until the context is
+// done or the channel is closed, a fake channel can be used for testing.
+func (ox *Handler) Run(ctx context.Context, sigs <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case sig, ok := <-sigs:
+			if !ok {
+				return
+			}
+
+			ox.handle(sig)
+		}
+	}
+}
+
+func (ox *Handler) handle(sig os.Signal) {
+	action := ox.Policy[sig]
+	logger.Infof("signals: received '%s', action: %s.", sig, action)
+
+	switch action {
+	case ActionGracefulShutdown, ActionImmediateShutdown:
+		switch count := atomic.AddInt32(&ox.shutdownCount, 1); {
+		case count == 1:
+			if !ox.deliver(action) {
+				logger.Infof("signals: the shutdown is already in progress, '%s' is ignored.", sig)
+			}
+		case count == 2 && ox.Abort != nil:
+			logger.Errf("signals: received '%s' during shutdown, aborting.", sig)
+			ox.Abort(sig)
+		default:
+			ox.forceExit(sig)
+		}
+
+	case ActionReload:
+		if ox.Reload == nil {
+			logger.Infof("signals: reload is not supported, '%s' is ignored.", sig)
+			return
+		}
+
+		ox.Reload()
+
+	case ActionDumpGoroutines:
+		out := ox.DumpOutput
+		if out == nil {
+			out = os.Stderr
+		}
+
+		if err := pprof.Lookup("goroutine").WriteTo(out, 2); err != nil {
+			logger.Errf("signals: failed to dump the goroutines: %+v", err)
+		}
+	}
+}
+
+// Raise sends the signal to the process itself, e.g. to start the shutdown of
+// a runner that only handles the signals by itself.
+func Raise(sig os.Signal) error {
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		return err
+	}
+
+	return process.Signal(sig)
+}
+
+func (ox *Handler) forceExit(sig os.Signal) {
+	if ox.ForceExit != nil {
+		ox.ForceExit(sig)
+		return
+	}
+
+	logger.Errf("signals: received '%s' during shutdown, forcing exit.", sig)
+	shutdown.Exit(shutdown.ExitCodeForced)
+}
@@ -0,0 +1,218 @@
+package signals
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// outcome counts what the handler did with the received signals.
+type outcome struct {
+	shutdown  []Action
+	reload    int
+	abort     int
+	forceExit int
+	dumped    bool
+}
+
+// run feeds the signals to a handler through a fake channel, and returns once
+// every one of them has been handled.
+func run(policy Policy, withAbort bool, sigs ...os.Signal) outcome {
+	var out outcome
+	var dump bytes.Buffer
+
+	handler := NewHandler(policy)
+	handler.DumpOutput = &dump
+	handler.Reload = func() {
+		out.reload++
+	}
+	handler.ForceExit = func(sig os.Signal) {
+		out.forceExit++
+	}
+	if withAbort {
+		handler.Abort = func(sig os.Signal) {
+			out.abort++
+		}
+	}
+
+	ch := make(chan os.Signal, len(sigs))
+	for _, sig := range sigs {
+		ch <- sig
+	}
+	close(ch)
+
+	handler.Run(context.Background(), ch)
+
+	select {
+	case action := <-handler.Shutdown():
+		out.shutdown = append(out.shutdown, action)
+	default:
+	}
+
+	out.dumped = strings.Contains(dump.String(), "goroutine")
+	return out
+}
+
+func TestHandlerPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy Policy
+		sig    os.Signal
+		want   outcome
+	}{
+		{
+			name:   "graceful shutdown",
+			policy: DefaultPolicy(),
+			sig:    syscall.SIGTERM,
+			want:   outcome{shutdown: []Action{ActionGracefulShutdown}},
+		},
+		{
+			name:   "immediate shutdown",
+			policy: Policy{syscall.SIGINT: ActionImmediateShutdown},
+			sig:    syscall.SIGINT,
+			want:   outcome{shutdown: []Action{ActionImmediateShutdown}},
+		},
+		{
+			name:   "reload",
+			policy: DefaultPolicy(),
+			sig:    syscall.SIGHUP,
+			want:   outcome{reload: 1},
+		},
+		{
+			name:   "dump goroutines",
+			policy: DefaultPolicy(),
+			sig:    syscall.SIGQUIT,
+			want:   outcome{dumped: true},
+		},
+		{
+			name:   "ignore",
+			policy: Policy{syscall.SIGHUP: ActionIgnore},
+			sig:    syscall.SIGHUP,
+			want:   outcome{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := run(tt.policy, true, tt.sig)
+			assertOutcome(t, got, tt.want)
+		})
+	}
+}
+
+func TestHandlerEscalation(t *testing.T) {
+	tests := []struct {
+		name      string
+		withAbort bool
+		sigs      []os.Signal
+		want      outcome
+	}{
+		{
+			name:      "first signal",
+			withAbort: true,
+			sigs:      []os.Signal{syscall.SIGTERM},
+			want:      outcome{shutdown: []Action{ActionGracefulShutdown}},
+		},
+		{
+			name:      "second signal aborts",
+			withAbort: true,
+			sigs:      []os.Signal{syscall.SIGTERM, syscall.SIGINT},
+			want:      outcome{shutdown: []Action{ActionGracefulShutdown}, abort: 1},
+		},
+		{
+			name:      "third signal forces exit",
+			withAbort: true,
+			sigs:      []os.Signal{syscall.SIGTERM, syscall.SIGTERM, syscall.SIGTERM},
+			want:      outcome{shutdown: []Action{ActionGracefulShutdown}, abort: 1, forceExit: 1},
+		},
+		{
+			name:      "second signal forces exit without abort",
+			withAbort: false,
+			sigs:      []os.Signal{syscall.SIGTERM, syscall.SIGTERM},
+			want:      outcome{shutdown: []Action{ActionGracefulShutdown}, forceExit: 1},
+		},
+		{
+			name:      "other signals don't escalate",
+			withAbort: true,
+			sigs:      []os.Signal{syscall.SIGTERM, syscall.SIGHUP, syscall.SIGHUP},
+			want:      outcome{shutdown: []Action{ActionGracefulShutdown}, reload: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := run(DefaultPolicy(), tt.withAbort, tt.sigs...)
+			assertOutcome(t, got, tt.want)
+		})
+	}
+}
+
+func assertOutcome(t *testing.T, got outcome, want outcome) {
+	t.Helper()
+
+	if len(got.shutdown) != len(want.shutdown) || (len(got.shutdown) > 0 && got.shutdown[0] != want.shutdown[0]) {
+		t.Errorf("shutdown = %v, want %v", got.shutdown, want.shutdown)
+	}
+
+	if got.reload != want.reload {
+		t.Errorf("reload = %d, want %d", got.reload, want.reload)
+	}
+
+	if got.abort != want.abort {
+		t.Errorf("abort = %d, want %d", got.abort, want.abort)
+	}
+
+	if got.forceExit != want.forceExit {
+		t.Errorf("force exit = %d, want %d", got.forceExit, want.forceExit)
+	}
+
+	if got.dumped != want.dumped {
+		t.Errorf("dumped = %v, want %v", got.dumped, want.dumped)
+	}
+}
+
+func TestHandlerRequestShutdown(t *testing.T) {
+	var aborted int
+
+	handler := NewHandler(DefaultPolicy())
+	handler.Abort = func(sig os.Signal) {
+		aborted++
+	}
+	handler.ForceExit = func(sig os.Signal) {
+		t.Errorf("forced exit on '%s'", sig)
+	}
+
+	handler.RequestShutdown()
+	if action := <-handler.Shutdown(); action != ActionGracefulShutdown {
+		t.Fatalf("action = %s, want %s", action, ActionGracefulShutdown)
+	}
+
+	// the SIGTERM following the request is the first signal, only the next
+	// one aborts.
+	ch := make(chan os.Signal, 1)
+	ch <- syscall.SIGTERM
+	close(ch)
+	handler.Run(context.Background(), ch)
+
+	if aborted != 0 {
+		t.Fatalf("aborted on the first signal after the request")
+	}
+
+	select {
+	case action := <-handler.Shutdown():
+		t.Fatalf("shutdown delivered twice, the second time with %s", action)
+	default:
+	}
+
+	ch = make(chan os.Signal, 1)
+	ch <- syscall.SIGTERM
+	close(ch)
+	handler.Run(context.Background(), ch)
+
+	if aborted != 1 {
+		t.Errorf("aborted %d times, want once", aborted)
+	}
+}
@@ -0,0 +1,145 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koinworks/asgard-heimdal/libs/logger"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+	"github.com/luthfikw/example.graceful-shutdown/internal/shutdown"
+)
+
+var ErrAlreadyStarted = errors.New("startup sequence already started")
+
+// StartError is returned when a step fails to start, along with the error of
+// rolling back the steps started before it.
+type StartError struct {
+	Name        string
+	Err         error
+	RollbackErr error
+}
+
+func (ox *StartError) Error() string {
+	if ox.RollbackErr != nil {
+		return fmt.Sprintf("failed to start '%s': %v; rollback: %v", ox.Name, ox.Err, ox.RollbackErr)
+	}
+
+	return fmt.Sprintf("failed to start '%s': %v", ox.Name, ox.Err)
+}
+
+func (ox *StartError) Unwrap() error {
+	return ox.Err
+}
+
+type step struct {
+	name      string
+	startable component.Startable
+	disposer  component.ContextDisposableComponent
+}
+
+// Sequence starts the steps in the order they're added, when one of them
+// fails, the steps already started are disposed in the reverse order.
+type Sequence struct {
+	// RollbackTimeout limits the whole rollback, zero means no limit.
+	RollbackTimeout time.Duration
+
+	mu      sync.Mutex
+	steps   []step
+	started []step
+	begun   bool
+}
+
+func NewSequence(rollbackTimeout time.Duration) *Sequence {
+	return &Sequence{
+		RollbackTimeout: rollbackTimeout,
+	}
+}
+
+// Add appends a step, the startable may be nil for a step that's already
+// running, e.g. a client that doesn't need to connect, so it's only disposed
+// on rollback. The disposer may be nil for a step with nothing to dispose.
+func (ox *Sequence) Add(name string, startable component.Startable, disposer component.ContextDisposableComponent) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.steps = append(ox.steps, step{
+		name:      name,
+		startable: startable,
+		disposer:  disposer,
+	})
+}
+
+// Start starts every step until one of them fails, then rolls back the
+// started ones and returns a StartError. The rollback doesn't derive from the
+// ctx, since it's likely done by then.
+func (ox *Sequence) Start(ctx context.Context) error {
+	ox.mu.Lock()
+	if ox.begun {
+		ox.mu.Unlock()
+		return ErrAlreadyStarted
+	}
+	ox.begun = true
+	steps := ox.steps
+	ox.mu.Unlock()
+
+	for _, s := range steps {
+		if s.startable != nil {
+			logger.Infof("startup: starting '%s'...", s.name)
+			if err := component.SafeCall(func() error { return s.startable.Start(ctx) }); err != nil {
+				logger.Errf("startup: failed to start '%s': %+v", s.name, err)
+				return &StartError{
+					Name:        s.name,
+					Err:         err,
+					RollbackErr: ox.Rollback(),
+				}
+			}
+		}
+
+		ox.mu.Lock()
+		ox.started = append(ox.started, s)
+		ox.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Rollback disposes the started steps one by one in the reverse order within
+// the rollback timeout, e.g. when the service fails right after its startup.
+// A step is only disposed once.
+func (ox *Sequence) Rollback() error {
+	ctx := context.Background()
+	if ox.RollbackTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ox.RollbackTimeout)
+		defer cancel()
+	}
+
+	ox.mu.Lock()
+	started := ox.started
+	ox.started = nil
+	ox.mu.Unlock()
+
+	var errs shutdown.MultiError
+	for i := len(started) - 1; i >= 0; i-- {
+		s := started[i]
+		if s.disposer == nil {
+			continue
+		}
+
+		logger.Infof("startup: rolling back '%s'...", s.name)
+		if err := component.SafeCall(func() error { return s.disposer.DisposeContext(ctx) }); err != nil {
+			logger.Errf("startup: error during rolling back '%s': %+v", s.name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return errs
+}
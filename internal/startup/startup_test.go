@@ -0,0 +1,173 @@
+package startup
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/luthfikw/example.graceful-shutdown/internal/component"
+)
+
+// events records the starts and disposals of the steps in order.
+type events struct {
+	mu   sync.Mutex
+	list []string
+}
+
+func (ox *events) add(event string) {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	ox.list = append(ox.list, event)
+}
+
+func (ox *events) get() []string {
+	ox.mu.Lock()
+	defer ox.mu.Unlock()
+
+	return append([]string(nil), ox.list...)
+}
+
+// add appends a step starting with the startErr, or panicking with the
+// startPanic.
+func add(sequence *Sequence, log *events, name string, startErr error, startPanic interface{}) {
+	sequence.Add(name, component.StartFunc(func(ctx context.Context) error {
+		if startPanic != nil {
+			panic(startPanic)
+		}
+
+		if startErr != nil {
+			return startErr
+		}
+
+		log.add("start " + name)
+		return nil
+	}), component.DisposeContextFunc(func(ctx context.Context) error {
+		log.add("dispose " + name)
+		return nil
+	}))
+}
+
+func TestSequenceStart(t *testing.T) {
+	log := &events{}
+	sequence := NewSequence(0)
+	add(sequence, log, "a", nil, nil)
+	add(sequence, log, "b", nil, nil)
+
+	if err := sequence.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"start a", "start b"}; !reflect.DeepEqual(log.get(), want) {
+		t.Errorf("events = %v, want %v", log.get(), want)
+	}
+
+	if err := sequence.Start(context.Background()); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("second start = %v, want %v", err, ErrAlreadyStarted)
+	}
+}
+
+func TestSequenceRollback(t *testing.T) {
+	log := &events{}
+	sequence := NewSequence(0)
+	add(sequence, log, "a", nil, nil)
+	add(sequence, log, "b", nil, nil)
+	sequence.Add("c", nil, component.DisposeContextFunc(func(ctx context.Context) error {
+		log.add("dispose c")
+		return errors.New("failed to dispose c")
+	}))
+	sequence.Add("d", nil, nil)
+
+	if err := sequence.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the step without a disposer is skipped, the failed one doesn't stop
+	// the rest.
+	err := sequence.Rollback()
+	if err == nil {
+		t.Error("the rollback error of 'c' is missing")
+	}
+
+	want := []string{"start a", "start b", "dispose c", "dispose b", "dispose a"}
+	if !reflect.DeepEqual(log.get(), want) {
+		t.Errorf("events = %v, want %v", log.get(), want)
+	}
+
+	// a step is only disposed once.
+	if err := sequence.Rollback(); err != nil {
+		t.Errorf("second rollback = %v", err)
+	}
+	if got := log.get(); len(got) != len(want) {
+		t.Errorf("events after the second rollback = %v, want %v", got, want)
+	}
+}
+
+func TestSequenceStartFailure(t *testing.T) {
+	failure := errors.New("failed to start b")
+
+	log := &events{}
+	sequence := NewSequence(0)
+	add(sequence, log, "a", nil, nil)
+	add(sequence, log, "b", failure, nil)
+	add(sequence, log, "c", nil, nil)
+
+	err := sequence.Start(context.Background())
+
+	var startErr *StartError
+	if !errors.As(err, &startErr) || startErr.Name != "b" || startErr.RollbackErr != nil {
+		t.Fatalf("err = %v, want a StartError of 'b'", err)
+	}
+	if !errors.Is(err, failure) {
+		t.Errorf("err = %v, want it to wrap %v", err, failure)
+	}
+
+	// the failed step isn't disposed, and the ones after it never start.
+	if want := []string{"start a", "dispose a"}; !reflect.DeepEqual(log.get(), want) {
+		t.Errorf("events = %v, want %v", log.get(), want)
+	}
+}
+
+func TestSequenceStartPanic(t *testing.T) {
+	log := &events{}
+	sequence := NewSequence(0)
+	add(sequence, log, "a", nil, nil)
+	add(sequence, log, "b", nil, "boom")
+	add(sequence, log, "c", nil, nil)
+
+	err := sequence.Start(context.Background())
+
+	var startErr *StartError
+	var panicErr *component.PanicError
+	if !errors.As(err, &startErr) || startErr.Name != "b" || !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Fatalf("err = %v, want the recovered panic of 'b'", err)
+	}
+
+	if want := []string{"start a", "dispose a"}; !reflect.DeepEqual(log.get(), want) {
+		t.Errorf("events = %v, want %v", log.get(), want)
+	}
+}
+
+func TestSequenceStartCancelled(t *testing.T) {
+	log := &events{}
+	sequence := NewSequence(0)
+	add(sequence, log, "a", nil, nil)
+	sequence.Add("b", component.StartFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sequence.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want %v", err, context.Canceled)
+	}
+
+	// the rollback doesn't derive from the cancelled context.
+	if want := []string{"start a", "dispose a"}; !reflect.DeepEqual(log.get(), want) {
+		t.Errorf("events = %v, want %v", log.get(), want)
+	}
+}